@@ -5,17 +5,18 @@ import (
 	crypto2 "crypto"
 	ecdsa2 "crypto/ecdsa"
 	"crypto/ed25519"
-	"crypto/sha256"
-	"encoding/asn1"
-	"encoding/hex"
+	rsa2 "crypto/rsa"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"os"
 
 	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/bls"
 	"github.com/meshplus/bitxhub-kit/crypto/asym/ecdsa"
-	"github.com/meshplus/bitxhub-kit/crypto/sym"
+	ed25519key "github.com/meshplus/bitxhub-kit/crypto/asym/ed25519"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/rsa"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/schnorr"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/sm2"
 	"github.com/meshplus/bitxhub-kit/types"
 )
 
@@ -31,10 +32,96 @@ type Crypto struct {
 	UnmarshalPrivateKey CryptoUnmarshalPrivateKey
 }
 
+func init() {
+	RegisterCrypto(crypto.SM2, newSM2, verifySM2, unmarshalSM2)
+	RegisterCrypto(crypto.BLS12_381, newBLS, verifyBLS, unmarshalBLS)
+	RegisterCrypto(crypto.Secp256k1Schnorr, newSchnorr, verifySchnorr, unmarshalSchnorr)
+	RegisterBatchVerify(crypto.Secp256k1Schnorr, batchVerifySchnorr)
+}
+
+// RegisterCrypto registers the implementation of a key type, replacing any
+// implementation registered before.
 func RegisterCrypto(typ crypto.KeyType, f CryptoConstructor, g CryptoVerify, k CryptoUnmarshalPrivateKey) {
-	CryptoM[typ].Constructor = f
-	CryptoM[typ].Verify = g
-	CryptoM[typ].UnmarshalPrivateKey = k
+	CryptoM[typ] = &Crypto{
+		Constructor:         f,
+		Verify:              g,
+		UnmarshalPrivateKey: k,
+	}
+}
+
+func newSM2(opt crypto.KeyType) (crypto.PrivateKey, error) {
+	return sm2.New()
+}
+
+func verifySM2(opt crypto.KeyType, sig, digest []byte, from types.Address) (bool, error) {
+	pubkey, err := sm2.SigToPub(sig)
+	if err != nil {
+		return false, err
+	}
+
+	expected, err := pubkey.Address()
+	if err != nil {
+		return false, err
+	}
+
+	if expected.String() != from.String() {
+		return false, fmt.Errorf("wrong singer for this signature")
+	}
+	return pubkey.Verify(digest, sig)
+}
+
+func unmarshalSM2(data []byte, opt crypto.KeyType) (crypto.PrivateKey, error) {
+	return sm2.UnmarshalPrivateKey(data)
+}
+
+func newBLS(opt crypto.KeyType) (crypto.PrivateKey, error) {
+	return bls.New()
+}
+
+func verifyBLS(opt crypto.KeyType, sig, digest []byte, from types.Address) (bool, error) {
+	pubkey, err := bls.SigToPub(sig)
+	if err != nil {
+		return false, err
+	}
+
+	expected, err := pubkey.Address()
+	if err != nil {
+		return false, err
+	}
+
+	if expected.String() != from.String() {
+		return false, fmt.Errorf("wrong singer for this signature")
+	}
+	return pubkey.Verify(digest, sig)
+}
+
+func unmarshalBLS(data []byte, opt crypto.KeyType) (crypto.PrivateKey, error) {
+	return bls.UnmarshalPrivateKey(data)
+}
+
+func newSchnorr(opt crypto.KeyType) (crypto.PrivateKey, error) {
+	return schnorr.New()
+}
+
+func verifySchnorr(opt crypto.KeyType, sig, digest []byte, from types.Address) (bool, error) {
+	pubkey, err := schnorr.SigToPub(sig)
+	if err != nil {
+		return false, err
+	}
+
+	expected, err := pubkey.Address()
+	if err != nil {
+		return false, err
+	}
+
+	if expected.String() != from.String() {
+		return false, fmt.Errorf("wrong singer for this signature")
+	}
+	return pubkey.Verify(digest, sig)
+}
+
+func unmarshalSchnorr(data []byte, opt crypto.KeyType) (crypto.PrivateKey, error) {
+	return schnorr.UnmarshalPrivateKey(data)
 }
 
 func GetCrypto(typ crypto.KeyType) (*Crypto, error) {
@@ -49,21 +136,27 @@ func SupportKeyType() map[crypto.KeyType]string {
 	if len(supportCryptoTypeToName) != 0 {
 		return supportCryptoTypeToName
 	}
-	if _, ok := CryptoM[crypto.SM2]; ok {
-		return map[crypto.KeyType]string{
-			crypto.Secp256k1:  "Secp256k1",
-			crypto.ECDSA_P256: "ECDSA_P256",
-			crypto.ECDSA_P384: "ECDSA_P384",
-			crypto.ECDSA_P521: "ECDSA_P521",
-			crypto.SM2:        "SM2",
-		}
-	}
-	return map[crypto.KeyType]string{
+	supported := map[crypto.KeyType]string{
 		crypto.Secp256k1:  "Secp256k1",
 		crypto.ECDSA_P256: "ECDSA_P256",
 		crypto.ECDSA_P384: "ECDSA_P384",
 		crypto.ECDSA_P521: "ECDSA_P521",
+		crypto.Ed25519:    "Ed25519",
+		crypto.RSA:        "RSA",
+	}
+	for typ := range CryptoM {
+		supported[typ] = keyTypeName(typ)
+	}
+	return supported
+}
+
+func keyTypeName(typ crypto.KeyType) string {
+	for name, t := range crypto.CryptoNameType {
+		if t == typ {
+			return name
+		}
 	}
+	return fmt.Sprintf("KeyType(%d)", typ)
 }
 
 func GetConfiguredKeyType() map[crypto.KeyType]string {
@@ -89,35 +182,38 @@ func ConfiguredKeyType(algorithms []string) error {
 func GenerateKeyPair(opt crypto.KeyType) (crypto.PrivateKey, error) {
 	switch opt {
 	case crypto.RSA:
-		return nil, fmt.Errorf("don`t support rsa algorithm currently")
+		return GenerateRSAKeyPair(rsa.DefaultBits)
 	case crypto.ECDSA_P256, crypto.ECDSA_P384, crypto.ECDSA_P521, crypto.Secp256k1:
 		return ecdsa.New(opt)
 	case crypto.Ed25519:
-		return nil, fmt.Errorf("don`t support ed25519 algorithm currently")
-	case crypto.SM2:
+		return ed25519key.New()
+	default:
 		cryptoCon, err := GetCrypto(opt)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("wrong algorithm type")
 		}
 		return cryptoCon.Constructor(opt)
-	default:
-		return nil, fmt.Errorf("wrong algorithm type")
 	}
 }
 
+// GenerateRSAKeyPair generates a rsa private key of the given size
+func GenerateRSAKeyPair(bits int) (crypto.PrivateKey, error) {
+	return rsa.New(bits)
+}
+
 // SupportedKeyType: check if configuration algorithm supported in bitxhub
 func SupportedKeyType(typ crypto.KeyType) bool {
 	if typ == crypto.ECDSA_P256 ||
 		typ == crypto.ECDSA_P384 ||
 		typ == crypto.ECDSA_P521 ||
-		typ == crypto.Secp256k1 {
+		typ == crypto.Secp256k1 ||
+		typ == crypto.Ed25519 ||
+		typ == crypto.RSA {
 		return true
-	} else if typ == crypto.SM2 {
-		_, ok := CryptoM[typ]
-		return ok
 	}
 
-	return false
+	_, ok := CryptoM[typ]
+	return ok
 }
 
 // Sign signs digest using key k and add key type flag in the beginning.
@@ -159,7 +255,20 @@ func VerifyWithType(sig, digest []byte, from types.Address) (bool, error) {
 func Verify(opt crypto.KeyType, sig, digest []byte, from types.Address) (bool, error) {
 	switch opt {
 	case crypto.RSA:
-		return false, fmt.Errorf("don`t support rsa algorithm currently")
+		pubkey, err := rsa.SigToPub(sig)
+		if err != nil {
+			return false, err
+		}
+
+		expected, err := pubkey.Address()
+		if err != nil {
+			return false, err
+		}
+
+		if expected.String() != from.String() {
+			return false, fmt.Errorf("wrong singer for this signature")
+		}
+		return pubkey.Verify(digest, sig)
 	case crypto.Secp256k1:
 		pubKeyBytes, err := ecdsa.Ecrecover(digest, sig)
 		if err != nil {
@@ -181,13 +290,22 @@ func Verify(opt crypto.KeyType, sig, digest []byte, from types.Address) (bool, e
 
 		return true, nil
 	case crypto.ECDSA_P256, crypto.ECDSA_P384, crypto.ECDSA_P521:
-		sigStuct := &ecdsa.Sig{}
-		_, err := asn1.Unmarshal(sig, sigStuct)
+		pubkey, err := ecdsa.SigToPubKey(opt, digest, sig)
+		if err != nil {
+			return false, err
+		}
+
+		expected, err := pubkey.Address()
 		if err != nil {
 			return false, err
 		}
 
-		pubkey, err := ecdsa.UnmarshalPublicKey(sigStuct.Pub, opt)
+		if expected.String() != from.String() {
+			return false, fmt.Errorf("wrong singer for this signature")
+		}
+		return pubkey.Verify(digest, sig)
+	case crypto.Ed25519:
+		pubkey, err := ed25519key.SigToPub(sig)
 		if err != nil {
 			return false, err
 		}
@@ -201,16 +319,12 @@ func Verify(opt crypto.KeyType, sig, digest []byte, from types.Address) (bool, e
 			return false, fmt.Errorf("wrong singer for this signature")
 		}
 		return pubkey.Verify(digest, sig)
-	case crypto.Ed25519:
-		return false, fmt.Errorf("don`t support ed25519 algorithm currently")
-	case crypto.SM2:
+	default:
 		cryptoCon, err := GetCrypto(opt)
 		if err != nil {
-			return false, err
+			return false, fmt.Errorf("wrong algorithm type")
 		}
 		return cryptoCon.Verify(opt, sig, digest, from)
-	default:
-		return false, fmt.Errorf("wrong algorithm type")
 	}
 }
 
@@ -219,8 +333,12 @@ func PrivateKeyFromStdKey(priv crypto2.PrivateKey) (crypto.PrivateKey, error) {
 	switch key := priv.(type) {
 	case *ecdsa2.PrivateKey:
 		return ecdsa.NewWithCryptoKey(key)
+	case *rsa2.PrivateKey:
+		return rsa.NewWithCryptoKey(key)
+	case ed25519.PrivateKey:
+		return ed25519key.NewWithCryptoKey(key)
 	case *ed25519.PrivateKey:
-		return nil, fmt.Errorf("don't support this algorithm")
+		return ed25519key.NewWithCryptoKey(*key)
 	default:
 		return nil, fmt.Errorf("don't support this algorithm")
 	}
@@ -230,8 +348,12 @@ func PubKeyFromStdKey(pub crypto2.PublicKey) (crypto.PublicKey, error) {
 	switch key := pub.(type) {
 	case *ecdsa2.PublicKey:
 		return ecdsa.NewPublicKey(*key)
+	case *rsa2.PublicKey:
+		return rsa.NewPublicKey(key)
+	case ed25519.PublicKey:
+		return ed25519key.NewPublicKey(key)
 	case *ed25519.PublicKey:
-		return nil, fmt.Errorf("don't support this algorithm")
+		return ed25519key.NewPublicKey(*key)
 	default:
 		return nil, fmt.Errorf("don't support this algorithm")
 	}
@@ -251,68 +373,27 @@ func PubKeyToStdKey(pub crypto.PublicKey) (crypto2.PublicKey, error) {
 	switch key := pub.(type) {
 	case *ecdsa.PublicKey:
 		return key.K, nil
+	case *ed25519key.PublicKey:
+		return key.K, nil
+	case *rsa.PublicKey:
+		return key.K, nil
 	default:
 		return ecdsa2.PublicKey{}, fmt.Errorf("don't support this algorithm")
 	}
 }
 
-func StorePrivateKey(priv crypto.PrivateKey, keyFilePath, password string) error {
-	keyStore, err := GenKeyStore(priv, password)
-	if err != nil {
-		return err
-	}
-
-	filePtr, err := os.Create(keyFilePath)
-	if err != nil {
-		return err
-	}
-
-	data, err := json.MarshalIndent(keyStore, "", " ")
-	if err != nil {
-		return err
-	}
-
-	_, err = filePtr.Write(data)
+// StorePrivateKey writes the private key to a version 3 keystore file
+func StorePrivateKey(priv crypto.PrivateKey, keyFilePath, password string, opts ...KeyStoreOption) error {
+	keyStore, err := GenKeyStore(priv, password, opts...)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func GenKeyStore(priv crypto.PrivateKey, password string) (*crypto.KeyStore, error) {
-	var cipherText string
-
-	privBytes, err := priv.Bytes()
-	if err != nil {
-		return nil, err
-	}
-	if password != "" {
-		hash := sha256.Sum256([]byte(password))
-		aesKey, err := sym.GenerateSymKey(crypto.AES, hash[:])
-		if err != nil {
-			return nil, err
-		}
-
-		encrypted, err := aesKey.Encrypt(privBytes)
-		if err != nil {
-			return nil, err
-		}
-
-		cipherText = hex.EncodeToString(encrypted)
-	} else {
-		cipherText = hex.EncodeToString(privBytes)
-	}
-
-	return &crypto.KeyStore{
-		Type: priv.Type(),
-		Cipher: &crypto.CipherKey{
-			Cipher: "AES-256",
-			Data:   cipherText,
-		},
-	}, nil
+	return StoreKeyStore(keyStore, keyFilePath)
 }
 
+// RestorePrivateKey reads a private key from a keystore file, both version 3
+// and legacy keystores are supported.
 func RestorePrivateKey(keyFilePath, password string) (crypto.PrivateKey, error) {
 	data, err := ioutil.ReadFile(keyFilePath)
 	if err != nil {
@@ -323,36 +404,42 @@ func RestorePrivateKey(keyFilePath, password string) (crypto.PrivateKey, error)
 		return nil, err
 	}
 
-	rawBytes, err := hex.DecodeString(keyStore.Cipher.Data)
-	if err != nil {
-		return nil, err
-	}
-
-	if password != "" {
-		hash := sha256.Sum256([]byte(password))
-		aesKey, err := sym.GenerateSymKey(crypto.AES, hash[:])
-		if err != nil {
-			return nil, err
-		}
+	return DecryptKeyStore(keyStore, password)
+}
 
-		rawBytes, err = aesKey.Decrypt(rawBytes)
+func unmarshalPrivateKey(rawBytes []byte, typ crypto.KeyType) (crypto.PrivateKey, error) {
+	switch typ {
+	case crypto.ECDSA_P256, crypto.ECDSA_P384, crypto.ECDSA_P521, crypto.Secp256k1:
+		return ecdsa.UnmarshalPrivateKey(rawBytes, typ)
+	case crypto.Ed25519:
+		return ed25519key.UnmarshalPrivateKey(rawBytes)
+	case crypto.RSA:
+		return rsa.UnmarshalPrivateKey(rawBytes)
+	default:
+		cryptoCon, err := GetCrypto(typ)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("don't support this private key")
 		}
+		return cryptoCon.UnmarshalPrivateKey(rawBytes, typ)
 	}
+}
 
-	switch keyStore.Type {
+// UnmarshalPublicKey parses the Bytes of a public key of the given type
+func UnmarshalPublicKey(data []byte, typ crypto.KeyType) (crypto.PublicKey, error) {
+	switch typ {
 	case crypto.ECDSA_P256, crypto.ECDSA_P384, crypto.ECDSA_P521, crypto.Secp256k1:
-		return ecdsa.UnmarshalPrivateKey(rawBytes, keyStore.Type)
-	case crypto.Ed25519, crypto.RSA:
-		return nil, fmt.Errorf("don't support this private key")
+		return ecdsa.UnmarshalPublicKey(data, typ)
+	case crypto.Ed25519:
+		return ed25519key.UnmarshalPublicKey(data)
+	case crypto.RSA:
+		return rsa.UnmarshalPublicKey(data)
 	case crypto.SM2:
-		cryptoCon, err := GetCrypto(keyStore.Type)
-		if err != nil {
-			return nil, err
-		}
-		return cryptoCon.UnmarshalPrivateKey(rawBytes, keyStore.Type)
+		return sm2.UnmarshalPublicKey(data)
+	case crypto.BLS12_381:
+		return bls.UnmarshalPublicKey(data)
+	case crypto.Secp256k1Schnorr:
+		return schnorr.UnmarshalPublicKey(data)
 	default:
-		return nil, fmt.Errorf("don't support this private key")
+		return nil, fmt.Errorf("don't support this public key")
 	}
 }
@@ -16,6 +16,11 @@ func TestSignAndVerifyPass(t *testing.T) {
 	testSignAndVerify(t, crypto.ECDSA_P384)
 	testSignAndVerify(t, crypto.ECDSA_P521)
 	testSignAndVerify(t, crypto.Secp256k1)
+	testSignAndVerify(t, crypto.Ed25519)
+	testSignAndVerify(t, crypto.RSA)
+	testSignAndVerify(t, crypto.SM2)
+	testSignAndVerify(t, crypto.BLS12_381)
+	testSignAndVerify(t, crypto.Secp256k1Schnorr)
 }
 
 func TestSignAndFail(t *testing.T) {
@@ -23,6 +28,11 @@ func TestSignAndFail(t *testing.T) {
 	testSignAndVerifyFail(t, crypto.ECDSA_P384)
 	testSignAndVerifyFail(t, crypto.ECDSA_P521)
 	testSignAndVerifyFail(t, crypto.Secp256k1)
+	testSignAndVerifyFail(t, crypto.Ed25519)
+	testSignAndVerifyFail(t, crypto.RSA)
+	testSignAndVerifyFail(t, crypto.SM2)
+	testSignAndVerifyFail(t, crypto.BLS12_381)
+	testSignAndVerifyFail(t, crypto.Secp256k1Schnorr)
 }
 
 func TestStorePrivateKey(t *testing.T) {
@@ -30,15 +40,20 @@ func TestStorePrivateKey(t *testing.T) {
 	testStore(t, crypto.ECDSA_P384)
 	testStore(t, crypto.ECDSA_P521)
 	testStore(t, crypto.Secp256k1)
+	testStore(t, crypto.Ed25519)
+	testStore(t, crypto.RSA)
+	testStore(t, crypto.SM2)
+	testStore(t, crypto.BLS12_381)
+	testStore(t, crypto.Secp256k1Schnorr)
 }
 
 func testStore(t *testing.T, opt crypto.KeyType) {
-	key, err := ecdsa.New(opt)
+	key, err := GenerateKeyPair(opt)
 	require.Nil(t, err)
 
 	keyFile := filepath.Join(os.TempDir(), "priv.json")
 
-	err = StorePrivateKey(key, keyFile, "key")
+	err = StorePrivateKey(key, keyFile, "key", WithLightScrypt())
 	require.Nil(t, err)
 
 	newKey, err := RestorePrivateKey(keyFile, "key")
@@ -90,6 +105,103 @@ func testSignAndVerifyFail(t *testing.T, opt crypto.KeyType) {
 	require.Equal(t, false, b)
 }
 
+func TestSignWithTypeEd25519(t *testing.T) {
+	digest := sha256.Sum256([]byte("hyperchain"))
+
+	priv, err := GenerateKeyPair(crypto.Ed25519)
+	require.Nil(t, err)
+
+	addr, err := priv.PublicKey().Address()
+	require.Nil(t, err)
+
+	sig, err := SignWithType(priv, digest[:])
+	require.Nil(t, err)
+
+	b, err := VerifyWithType(sig, digest[:], *addr)
+	require.Nil(t, err)
+	require.True(t, b)
+
+	other, err := GenerateKeyPair(crypto.Ed25519)
+	require.Nil(t, err)
+	otherAddr, err := other.PublicKey().Address()
+	require.Nil(t, err)
+	b, err = VerifyWithType(sig, digest[:], *otherAddr)
+	require.NotNil(t, err)
+	require.False(t, b)
+
+	stdPriv, err := PrivKeyToStdKey(priv)
+	require.NotNil(t, err)
+	require.Nil(t, stdPriv.D)
+
+	stdPub, err := PubKeyToStdKey(priv.PublicKey())
+	require.Nil(t, err)
+	pub, err := PubKeyFromStdKey(stdPub)
+	require.Nil(t, err)
+	require.Equal(t, priv.PublicKey(), pub)
+}
+
+func TestSignWithTypeBLS(t *testing.T) {
+	digest := sha256.Sum256([]byte("hyperchain"))
+
+	require.True(t, SupportedKeyType(crypto.BLS12_381))
+	require.Equal(t, "BLS12_381", SupportKeyType()[crypto.BLS12_381])
+
+	priv, err := GenerateKeyPair(crypto.BLS12_381)
+	require.Nil(t, err)
+	addr, err := priv.PublicKey().Address()
+	require.Nil(t, err)
+
+	sig, err := SignWithType(priv, digest[:])
+	require.Nil(t, err)
+	b, err := VerifyWithType(sig, digest[:], *addr)
+	require.Nil(t, err)
+	require.True(t, b)
+}
+
+func TestSignWithTypeCompact(t *testing.T) {
+	digest := sha256.Sum256([]byte("hyperchain"))
+
+	priv, err := GenerateKeyPair(crypto.ECDSA_P256)
+	require.Nil(t, err)
+	addr, err := priv.PublicKey().Address()
+	require.Nil(t, err)
+
+	require.Nil(t, priv.(*ecdsa.PrivateKey).SetEncoding(ecdsa.EncodingCompact))
+	sig, err := SignWithType(priv, digest[:])
+	require.Nil(t, err)
+	b, err := VerifyWithType(sig, digest[:], *addr)
+	require.Nil(t, err)
+	require.True(t, b)
+
+	// Raw signatures can only be verified with the public key
+	require.Nil(t, priv.(*ecdsa.PrivateKey).SetEncoding(ecdsa.EncodingRaw))
+	sig, err = SignWithType(priv, digest[:])
+	require.Nil(t, err)
+	b, err = VerifyWithType(sig, digest[:], *addr)
+	require.NotNil(t, err)
+	require.False(t, b)
+	b, err = priv.PublicKey().Verify(digest[:], sig[1:])
+	require.Nil(t, err)
+	require.True(t, b)
+}
+
+func TestRegisterCrypto(t *testing.T) {
+	const typ = crypto.KeyType(100)
+	_, err := GetCrypto(typ)
+	require.NotNil(t, err)
+
+	RegisterCrypto(typ, func(opt crypto.KeyType) (crypto.PrivateKey, error) {
+		return GenerateKeyPair(crypto.SM2)
+	}, nil, nil)
+	defer delete(CryptoM, typ)
+
+	con, err := GetCrypto(typ)
+	require.Nil(t, err)
+	priv, err := con.Constructor(typ)
+	require.Nil(t, err)
+	require.Equal(t, crypto.KeyType(crypto.SM2), priv.Type())
+}
+
 func BenchmarkSignSecp256k1(b *testing.B) {
 	digest := sha256.Sum256([]byte("hyperchain"))
 
@@ -0,0 +1,223 @@
+package asym
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/bls"
+	ed25519key "github.com/meshplus/bitxhub-kit/crypto/asym/ed25519"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/schnorr"
+	"github.com/meshplus/bitxhub-kit/types"
+)
+
+// batchChunkSize is the number of signatures a batch verifier checks at once,
+// large batches are split so that the chunks can run in parallel.
+const batchChunkSize = 64
+
+// VerifyItem is a signature checked by BatchVerify. Sig starts with the key
+// type flag, as produced by SignWithType.
+type VerifyItem struct {
+	Sig    []byte
+	Digest []byte
+	From   types.Address
+}
+
+// VerifyResult is the outcome of verifying a VerifyItem, Err explains why it
+// is invalid.
+type VerifyResult struct {
+	Valid bool
+	Err   error
+}
+
+// CryptoBatchVerify checks signatures of the same key type at once. The
+// signatures of the items are passed without the key type flag.
+type CryptoBatchVerify func(opt crypto.KeyType, items []VerifyItem) []VerifyResult
+
+var batchVerifiers = map[crypto.KeyType]CryptoBatchVerify{
+	crypto.Ed25519:   batchVerifyEd25519,
+	crypto.BLS12_381: batchVerifyBLS,
+}
+
+// RegisterBatchVerify registers the batch verification of a key type, which
+// BatchVerify uses instead of verifying its signatures one by one.
+func RegisterBatchVerify(typ crypto.KeyType, f CryptoBatchVerify) {
+	batchVerifiers[typ] = f
+}
+
+// BatchVerify verifies the items with one worker per CPU, see BatchVerifyWithWorkers.
+func BatchVerify(items []VerifyItem) []VerifyResult {
+	return BatchVerifyWithWorkers(items, runtime.NumCPU())
+}
+
+// BatchVerifyWithWorkers verifies the items in parallel with at most workers
+// goroutines. Signatures of key types with a registered batch verifier are
+// checked together and give the same results as Verify. The result at index
+// i belongs to items[i].
+func BatchVerifyWithWorkers(items []VerifyItem, workers int) []VerifyResult {
+	var (
+		results = make([]VerifyResult, len(items))
+		jobs    []func()
+		batches = make(map[crypto.KeyType][]int)
+	)
+	supported := SupportKeyType()
+	for i := range items {
+		i := i
+		if len(items[i].Sig) == 0 {
+			results[i].Err = fmt.Errorf("empty signature")
+			continue
+		}
+		typ := crypto.KeyType(items[i].Sig[0])
+		if _, ok := supported[typ]; !ok {
+			results[i].Err = fmt.Errorf("key type %d is not supported", typ)
+			continue
+		}
+		if _, ok := batchVerifiers[typ]; ok {
+			batches[typ] = append(batches[typ], i)
+			continue
+		}
+		jobs = append(jobs, func() {
+			item := items[i]
+			results[i].Valid, results[i].Err = Verify(typ, item.Sig[1:], item.Digest, item.From)
+		})
+	}
+
+	for typ, indexes := range batches {
+		typ, verifier := typ, batchVerifiers[typ]
+		for start := 0; start < len(indexes); start += batchChunkSize {
+			end := start + batchChunkSize
+			if end > len(indexes) {
+				end = len(indexes)
+			}
+			chunk := indexes[start:end]
+			jobs = append(jobs, func() {
+				batch := make([]VerifyItem, len(chunk))
+				for j, i := range chunk {
+					batch[j] = items[i]
+					batch[j].Sig = items[i].Sig[1:]
+				}
+				for j, result := range verifier(typ, batch) {
+					results[chunk[j]] = result
+				}
+			})
+		}
+	}
+
+	runJobs(jobs, workers)
+	return results
+}
+
+func runJobs(jobs []func(), workers int) {
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg    sync.WaitGroup
+		queue = make(chan func())
+	)
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				job()
+			}
+		}()
+	}
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+}
+
+func batchVerifyEd25519(opt crypto.KeyType, items []VerifyItem) []VerifyResult {
+	return batchVerifyWith(items, func(sig []byte) (crypto.PublicKey, error) {
+		return ed25519key.SigToPub(sig)
+	}, func(pubs []crypto.PublicKey, digests, sigs [][]byte) (bool, error) {
+		keys := make([]*ed25519key.PublicKey, len(pubs))
+		for i, pub := range pubs {
+			keys[i] = pub.(*ed25519key.PublicKey)
+		}
+		return ed25519key.BatchVerify(keys, digests, sigs)
+	})
+}
+
+func batchVerifyBLS(opt crypto.KeyType, items []VerifyItem) []VerifyResult {
+	return batchVerifyWith(items, func(sig []byte) (crypto.PublicKey, error) {
+		return bls.SigToPub(sig)
+	}, func(pubs []crypto.PublicKey, digests, sigs [][]byte) (bool, error) {
+		keys := make([]*bls.PublicKey, len(pubs))
+		for i, pub := range pubs {
+			keys[i] = pub.(*bls.PublicKey)
+		}
+		return bls.BatchVerify(keys, digests, sigs)
+	})
+}
+
+func batchVerifySchnorr(opt crypto.KeyType, items []VerifyItem) []VerifyResult {
+	return batchVerifyWith(items, func(sig []byte) (crypto.PublicKey, error) {
+		return schnorr.SigToPub(sig)
+	}, func(pubs []crypto.PublicKey, digests, sigs [][]byte) (bool, error) {
+		keys := make([]*schnorr.PublicKey, len(pubs))
+		for i, pub := range pubs {
+			keys[i] = pub.(*schnorr.PublicKey)
+		}
+		return schnorr.BatchVerify(keys, digests, sigs)
+	})
+}
+
+// batchVerifyWith checks the signers of the items, verifies the signatures
+// of the right signers at once with batch and, if the batch fails, one by
+// one to find the invalid ones.
+func batchVerifyWith(items []VerifyItem, sigToPub func(sig []byte) (crypto.PublicKey, error),
+	batch func(pubs []crypto.PublicKey, digests, sigs [][]byte) (bool, error)) []VerifyResult {
+	var (
+		results = make([]VerifyResult, len(items))
+		pubs    []crypto.PublicKey
+		digests [][]byte
+		sigs    [][]byte
+		indexes []int
+	)
+	for i, item := range items {
+		pubkey, err := sigToPub(item.Sig)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		expected, err := pubkey.Address()
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		if expected.String() != item.From.String() {
+			results[i].Err = fmt.Errorf("wrong singer for this signature")
+			continue
+		}
+		pubs = append(pubs, pubkey)
+		digests = append(digests, item.Digest)
+		sigs = append(sigs, item.Sig)
+		indexes = append(indexes, i)
+	}
+	if len(indexes) == 0 {
+		return results
+	}
+
+	if ok, _ := batch(pubs, digests, sigs); ok {
+		for _, i := range indexes {
+			results[i].Valid = true
+		}
+		return results
+	}
+
+	// Find the invalid signatures
+	for j, i := range indexes {
+		results[i].Valid, results[i].Err = pubs[j].Verify(digests[j], sigs[j])
+	}
+	return results
+}
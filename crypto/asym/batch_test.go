@@ -0,0 +1,107 @@
+package asym
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/types"
+	"github.com/stretchr/testify/require"
+)
+
+func newVerifyItems(t testing.TB, opt crypto.KeyType, n int) []VerifyItem {
+	items := make([]VerifyItem, n)
+	for i := range items {
+		priv, err := GenerateKeyPair(opt)
+		require.Nil(t, err)
+		addr, err := priv.PublicKey().Address()
+		require.Nil(t, err)
+		digest := sha256.Sum256([]byte(fmt.Sprintf("tx %d", i)))
+		sig, err := SignWithType(priv, digest[:])
+		require.Nil(t, err)
+		items[i] = VerifyItem{Sig: sig, Digest: digest[:], From: *addr}
+	}
+	return items
+}
+
+func TestBatchVerify(t *testing.T) {
+	var items []VerifyItem
+	for _, opt := range []crypto.KeyType{crypto.Secp256k1, crypto.ECDSA_P256, crypto.Ed25519, crypto.SM2, crypto.BLS12_381, crypto.Secp256k1Schnorr} {
+		items = append(items, newVerifyItems(t, opt, 3)...)
+	}
+
+	for _, result := range BatchVerify(items) {
+		require.Nil(t, result.Err)
+		require.True(t, result.Valid)
+	}
+
+	// Break one Ed25519, one BLS and one Schnorr signature and the sender of
+	// another BLS one
+	items[7].Digest = items[8].Digest
+	items[12].Digest = items[13].Digest
+	items[14].From = types.Address{}
+	items[15].Digest = items[16].Digest
+	items = append(items, VerifyItem{}, VerifyItem{Sig: []byte{byte(crypto.AES)}})
+
+	results := BatchVerifyWithWorkers(items, 2)
+	require.Equal(t, len(items), len(results))
+	for i, result := range results {
+		switch i {
+		case 7, 12, 14, 15, 18, 19:
+			require.False(t, result.Valid, i)
+			require.NotNil(t, result.Err, i)
+		default:
+			require.True(t, result.Valid, i)
+			require.Nil(t, result.Err, i)
+		}
+	}
+
+	require.Empty(t, BatchVerify(nil))
+}
+
+func benchmarkVerify(b *testing.B, opt crypto.KeyType, n int, batch bool) {
+	items := newVerifyItems(b, opt, n)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if batch {
+			BatchVerify(items)
+			continue
+		}
+		for _, item := range items {
+			VerifyWithType(item.Sig, item.Digest, item.From)
+		}
+	}
+}
+
+func BenchmarkVerifySequentialSecp256k1(b *testing.B) {
+	benchmarkVerify(b, crypto.Secp256k1, 256, false)
+}
+
+func BenchmarkBatchVerifySecp256k1(b *testing.B) {
+	benchmarkVerify(b, crypto.Secp256k1, 256, true)
+}
+
+func BenchmarkVerifySequentialEd25519(b *testing.B) {
+	benchmarkVerify(b, crypto.Ed25519, 256, false)
+}
+
+func BenchmarkBatchVerifyEd25519(b *testing.B) {
+	benchmarkVerify(b, crypto.Ed25519, 256, true)
+}
+
+func BenchmarkVerifySequentialBLS(b *testing.B) {
+	benchmarkVerify(b, crypto.BLS12_381, 16, false)
+}
+
+func BenchmarkBatchVerifyBLS(b *testing.B) {
+	benchmarkVerify(b, crypto.BLS12_381, 16, true)
+}
+
+func BenchmarkVerifySequentialSchnorr(b *testing.B) {
+	benchmarkVerify(b, crypto.Secp256k1Schnorr, 256, false)
+}
+
+func BenchmarkBatchVerifySchnorr(b *testing.B) {
+	benchmarkVerify(b, crypto.Secp256k1Schnorr, 256, true)
+}
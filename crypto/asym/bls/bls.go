@@ -0,0 +1,342 @@
+// Package bls implements BLS signatures over BLS12-381 with public keys in G1
+// and signatures in G2, following the BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_
+// ciphersuite of the IRTF BLS signature draft which Ethereum 2.0 uses as well.
+// Signatures and public keys can be aggregated, rogue key attacks are
+// prevented with proofs of possession. The curve arithmetic is provided by
+// github.com/kilic/bls12-381.
+package bls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	bls12381 "github.com/kilic/bls12-381"
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/types"
+	"golang.org/x/crypto/sha3"
+)
+
+var _ crypto.PrivateKey = (*PrivateKey)(nil)
+var _ crypto.PublicKey = (*PublicKey)(nil)
+
+const (
+	PrivateKeyLength = 32
+	PublicKeyLength  = 48
+	// RawSignatureLength is the length of a bare, aggregatable signature
+	RawSignatureLength = 96
+	// SignatureLength is the length of a signature produced by PrivateKey.Sign,
+	// the public key followed by the raw signature.
+	SignatureLength = PublicKeyLength + RawSignatureLength
+)
+
+var (
+	sigDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+	popDST = []byte("BLS_POP_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+	// r is the order of G1 and G2
+	r = bls12381.NewG1().Q()
+)
+
+// PrivateKey BLS12-381 private key.
+type PrivateKey struct {
+	sk  *big.Int
+	pub *PublicKey
+}
+
+// PublicKey BLS12-381 public key.
+type PublicKey struct {
+	point *bls12381.PointG1
+	raw   []byte
+}
+
+// New generates a BLS12-381 private key
+func New() (*PrivateKey, error) {
+	buf := make([]byte, 48)
+	for {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		sk := new(big.Int).SetBytes(buf)
+		sk.Mod(sk, r)
+		if sk.Sign() != 0 {
+			return newPrivateKey(sk), nil
+		}
+	}
+}
+
+func newPrivateKey(sk *big.Int) *PrivateKey {
+	g1 := bls12381.NewG1()
+	return &PrivateKey{
+		sk:  sk,
+		pub: newPublicKey(g1.MulScalarBig(g1.New(), g1.One(), sk)),
+	}
+}
+
+func newPublicKey(point *bls12381.PointG1) *PublicKey {
+	return &PublicKey{point: point, raw: bls12381.NewG1().ToCompressed(point)}
+}
+
+// UnmarshalPrivateKey parses a 32 byte big endian private key
+func UnmarshalPrivateKey(data []byte) (*PrivateKey, error) {
+	if len(data) != PrivateKeyLength {
+		return nil, fmt.Errorf("invalid bls private key length %d", len(data))
+	}
+	sk := new(big.Int).SetBytes(data)
+	if sk.Sign() == 0 || sk.Cmp(r) >= 0 {
+		return nil, fmt.Errorf("invalid bls private key")
+	}
+	return newPrivateKey(sk), nil
+}
+
+// UnmarshalPublicKey parses a 48 byte compressed public key, the point must
+// be in G1 and not the identity.
+func UnmarshalPublicKey(data []byte) (*PublicKey, error) {
+	if len(data) != PublicKeyLength {
+		return nil, fmt.Errorf("invalid bls public key length %d", len(data))
+	}
+	g1 := bls12381.NewG1()
+	point, err := g1.FromCompressed(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bls public key: %w", err)
+	}
+	if g1.IsZero(point) {
+		return nil, fmt.Errorf("bls public key is the identity")
+	}
+	return newPublicKey(point), nil
+}
+
+// SigToPub returns the public key prefixed to a signature of PrivateKey.Sign
+func SigToPub(sig []byte) (*PublicKey, error) {
+	if len(sig) != SignatureLength {
+		return nil, fmt.Errorf("invalid bls signature length %d", len(sig))
+	}
+	return UnmarshalPublicKey(sig[:PublicKeyLength])
+}
+
+// Bytes returns the 32 byte big endian private key
+func (priv *PrivateKey) Bytes() ([]byte, error) {
+	if priv.sk == nil {
+		return nil, fmt.Errorf("BLSPrivateKey is nil")
+	}
+	out := make([]byte, PrivateKeyLength)
+	raw := priv.sk.Bytes()
+	copy(out[PrivateKeyLength-len(raw):], raw)
+	return out, nil
+}
+
+func (priv *PrivateKey) PublicKey() crypto.PublicKey {
+	return priv.pub
+}
+
+// Sign signs digest and prefixes the signature with the public key, since
+// bls public keys can't be recovered from signatures. The signature can be
+// passed to AggregateSignatures as is.
+func (priv *PrivateKey) Sign(digest []byte) ([]byte, error) {
+	sig, err := priv.signWithDST(digest, sigDST)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, priv.pub.raw...), sig...), nil
+}
+
+// ProvePossession signs the public key, which proves that the holder knows
+// the private key. Public keys must come with a verified proof before they
+// are aggregated.
+func (priv *PrivateKey) ProvePossession() ([]byte, error) {
+	return priv.signWithDST(priv.pub.raw, popDST)
+}
+
+func (priv *PrivateKey) signWithDST(msg, dst []byte) ([]byte, error) {
+	if priv.sk == nil {
+		return nil, fmt.Errorf("BLSPrivateKey is nil")
+	}
+	g2 := bls12381.NewG2()
+	h, err := g2.HashToCurve(msg, dst)
+	if err != nil {
+		return nil, err
+	}
+	return g2.ToCompressed(g2.MulScalarBig(h, h, priv.sk)), nil
+}
+
+func (priv *PrivateKey) Type() crypto.KeyType {
+	return crypto.BLS12_381
+}
+
+// Bytes returns the 48 byte compressed public key
+func (pub *PublicKey) Bytes() ([]byte, error) {
+	return append([]byte{}, pub.raw...), nil
+}
+
+// Address returns the last 20 bytes of keccak256(pub)
+func (pub *PublicKey) Address() (*types.Address, error) {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(pub.raw)
+	return types.NewAddress(hash.Sum(nil)[12:]), nil
+}
+
+// Verify checks a raw signature or a signature of PrivateKey.Sign
+func (pub *PublicKey) Verify(digest []byte, sig []byte) (bool, error) {
+	if len(sig) == SignatureLength {
+		if !bytes.Equal(sig[:PublicKeyLength], pub.raw) {
+			return false, fmt.Errorf("signature is from another public key")
+		}
+	}
+	return verify(pub.point, digest, sig, sigDST)
+}
+
+// VerifyPossession checks a proof of possession made by ProvePossession
+func (pub *PublicKey) VerifyPossession(proof []byte) (bool, error) {
+	return verify(pub.point, pub.raw, proof, popDST)
+}
+
+func (pub *PublicKey) Type() crypto.KeyType {
+	return crypto.BLS12_381
+}
+
+func verify(pub *bls12381.PointG1, msg, sig, dst []byte) (bool, error) {
+	point, err := decompressSignature(sig)
+	if err != nil {
+		return false, err
+	}
+	engine := bls12381.NewEngine()
+	h, err := engine.G2.HashToCurve(msg, dst)
+	if err != nil {
+		return false, err
+	}
+
+	// e(pub, H(msg)) == e(g1, sig), the engine brings points to affine form
+	// in place, so shared keys are copied
+	engine.AddPair(new(bls12381.PointG1).Set(pub), h)
+	engine.AddPairInv(engine.G1.One(), point)
+	if !engine.Check() {
+		return false, fmt.Errorf("invalid signature")
+	}
+	return true, nil
+}
+
+// decompressSignature accepts raw signatures and signatures of
+// PrivateKey.Sign, the point must be in G2.
+func decompressSignature(sig []byte) (*bls12381.PointG2, error) {
+	switch len(sig) {
+	case RawSignatureLength:
+	case SignatureLength:
+		sig = sig[PublicKeyLength:]
+	default:
+		return nil, fmt.Errorf("invalid bls signature length %d", len(sig))
+	}
+	point, err := bls12381.NewG2().FromCompressed(sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bls signature: %w", err)
+	}
+	return point, nil
+}
+
+// AggregateSignatures adds up signatures into a raw signature
+func AggregateSignatures(sigs [][]byte) ([]byte, error) {
+	if len(sigs) == 0 {
+		return nil, fmt.Errorf("no signatures to aggregate")
+	}
+	g2 := bls12381.NewG2()
+	agg := g2.Zero()
+	for _, sig := range sigs {
+		point, err := decompressSignature(sig)
+		if err != nil {
+			return nil, err
+		}
+		g2.Add(agg, agg, point)
+	}
+	return g2.ToCompressed(agg), nil
+}
+
+// AggregatePublicKeys adds up public keys, every key must have a verified
+// proof of possession.
+func AggregatePublicKeys(pubs []*PublicKey) (*PublicKey, error) {
+	if len(pubs) == 0 {
+		return nil, fmt.Errorf("no public keys to aggregate")
+	}
+	g1 := bls12381.NewG1()
+	agg := g1.Zero()
+	for _, pub := range pubs {
+		g1.Add(agg, agg, pub.point)
+	}
+	if g1.IsZero(agg) {
+		return nil, fmt.Errorf("aggregated public key is the identity")
+	}
+	return newPublicKey(agg), nil
+}
+
+// FastAggregateVerify checks an aggregated signature of the same digest by
+// all public keys, every key must have a verified proof of possession.
+func FastAggregateVerify(pubs []*PublicKey, digest, sig []byte) (bool, error) {
+	agg, err := AggregatePublicKeys(pubs)
+	if err != nil {
+		return false, err
+	}
+	return verify(agg.point, digest, sig, sigDST)
+}
+
+// AggregateVerify checks an aggregated signature of digests[i] by pubs[i]
+func AggregateVerify(pubs []*PublicKey, digests [][]byte, sig []byte) (bool, error) {
+	if len(pubs) == 0 || len(pubs) != len(digests) {
+		return false, fmt.Errorf("public keys and digests don't match")
+	}
+	point, err := decompressSignature(sig)
+	if err != nil {
+		return false, err
+	}
+
+	engine := bls12381.NewEngine()
+	for i, pub := range pubs {
+		h, err := engine.G2.HashToCurve(digests[i], sigDST)
+		if err != nil {
+			return false, err
+		}
+		engine.AddPair(new(bls12381.PointG1).Set(pub.point), h)
+	}
+	engine.AddPairInv(engine.G1.One(), point)
+	if !engine.Check() {
+		return false, fmt.Errorf("invalid signature")
+	}
+	return true, nil
+}
+
+// BatchVerify checks the signatures sigs[i] of digests[i] by pubs[i] at once.
+// It combines them with random weights, which costs one pairing per
+// signature instead of two. If it fails, at least one signature is invalid
+// and the signatures need to be checked one by one to find it.
+func BatchVerify(pubs []*PublicKey, digests [][]byte, sigs [][]byte) (bool, error) {
+	if len(pubs) == 0 || len(pubs) != len(digests) || len(pubs) != len(sigs) {
+		return false, fmt.Errorf("public keys, digests and signatures don't match")
+	}
+
+	var (
+		engine = bls12381.NewEngine()
+		aggSig = engine.G2.Zero()
+		buf    = make([]byte, 8)
+	)
+	for i, pub := range pubs {
+		point, err := decompressSignature(sigs[i])
+		if err != nil {
+			return false, err
+		}
+		h, err := engine.G2.HashToCurve(digests[i], sigDST)
+		if err != nil {
+			return false, err
+		}
+		if _, err := rand.Read(buf); err != nil {
+			return false, err
+		}
+		weight := new(big.Int).SetBytes(buf)
+		weight.SetBit(weight, 64, 1)
+
+		engine.AddPair(engine.G1.MulScalarBig(engine.G1.New(), pub.point, weight), h)
+		engine.G2.Add(aggSig, aggSig, engine.G2.MulScalarBig(point, point, weight))
+	}
+	engine.AddPairInv(engine.G1.One(), aggSig)
+	if !engine.Check() {
+		return false, fmt.Errorf("invalid signature in batch")
+	}
+	return true, nil
+}
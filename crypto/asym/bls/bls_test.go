@@ -0,0 +1,238 @@
+package bls
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	bls12381 "github.com/kilic/bls12-381"
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashToG2(t *testing.T) {
+	// Test vector from RFC 9380 appendix J.10.1
+	g2 := bls12381.NewG2()
+	p, err := g2.HashToCurve([]byte(""), []byte("QUUX-V01-CS02-with-BLS12381G2_XMD:SHA-256_SSWU_RO_"))
+	require.Nil(t, err)
+	require.Equal(t, "05cb8437535e20ecffaef7752baddf98034139c38452458baeefab379ba13dff5bf5dd71b72418717047f5b0f37da03d"+
+		"0141ebfbdca40eb85b87142e130ab689c673cf60f1a3e98d69335266f30d9b8d4ac44c1038e9dcdd5393faf5c41fb78a",
+		hex.EncodeToString(g2.ToBytes(p)[:2*PublicKeyLength]))
+}
+
+func TestSignVectors(t *testing.T) {
+	// Test vectors from the Ethereum 2.0 consensus spec tests, which use the
+	// same ciphersuite
+	tests := []struct {
+		priv, pub, msg, sig string
+	}{
+		{
+			priv: "263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3",
+			pub:  "a491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a",
+			msg:  "0000000000000000000000000000000000000000000000000000000000000000",
+			sig:  "b6ed936746e01f8ecf281f020953fbf1f01debd5657c4a383940b020b26507f6076334f91e2366c96e9ab279fb5158090352ea1c5b0c9274504f4f0e7053af24802e51e4568d164fe986834f41e55c8e850ce1f98458c0cfc9ab380b55285a55",
+		},
+		{
+			priv: "263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3",
+			pub:  "a491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a",
+			msg:  "5656565656565656565656565656565656565656565656565656565656565656",
+			sig:  "882730e5d03f6b42c3abc26d3372625034e1d871b65a8a6b900a56dae22da98abbe1b68f85e49fe7652a55ec3d0591c20767677e33e5cbb1207315c41a9ac03be39c2e7668edc043d6cb1d9fd93033caa8a1c5b0e84bedaeb6c64972503a43eb",
+		},
+		{
+			priv: "263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3",
+			pub:  "a491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a",
+			msg:  "abababababababababababababababababababababababababababababababab",
+			sig:  "91347bccf740d859038fcdcaf233eeceb2a436bcaaee9b2aa3bfb70efe29dfb2677562ccbea1c8e061fb9971b0753c240622fab78489ce96768259fc01360346da5b9f579e5da0d941e4c6ba18a0e64906082375394f337fa1af2b7127b0d121",
+		},
+		{
+			priv: "47b8192d77bf871b62e87859d653922725724a5c031afeabc60bcef5ff665138",
+			msg:  "0000000000000000000000000000000000000000000000000000000000000000",
+			sig:  "b23c46be3a001c63ca711f87a005c200cc550b9429d5f4eb38d74322144f1b63926da3388979e5321012fb1a0526bcd100b5ef5fe72628ce4cd5e904aeaa3279527843fae5ca9ca675f4f51ed8f83bbf7155da9ecc9663100a885d5dc6df96d9",
+		},
+		{
+			priv: "328388aff0d4a5b7dc9205abd374e7e98f3cd9f3418edb4eafda5fb16473d216",
+			msg:  "0000000000000000000000000000000000000000000000000000000000000000",
+			sig:  "948a7cb99f76d616c2c564ce9bf4a519f1bea6b0a624a02276443c245854219fabb8d4ce061d255af5330b078d5380681751aa7053da2c98bae898edc218c75f07e24d8802a17cd1f6833b71e58f5eb5b94208b4d0bb3848cecb075ea21be115",
+		},
+	}
+	for i, test := range tests {
+		raw, err := hex.DecodeString(test.priv)
+		require.Nil(t, err)
+		priv, err := UnmarshalPrivateKey(raw)
+		require.Nil(t, err)
+		pub, err := priv.PublicKey().Bytes()
+		require.Nil(t, err)
+		if test.pub != "" {
+			require.Equal(t, test.pub, hex.EncodeToString(pub), "test %d", i)
+		}
+
+		msg, err := hex.DecodeString(test.msg)
+		require.Nil(t, err)
+		sig, err := priv.Sign(msg)
+		require.Nil(t, err)
+		require.Equal(t, pub, sig[:PublicKeyLength])
+		require.Equal(t, test.sig, hex.EncodeToString(sig[PublicKeyLength:]), "test %d", i)
+
+		expected, err := hex.DecodeString(test.sig)
+		require.Nil(t, err)
+		ok, err := priv.PublicKey().Verify(msg, expected)
+		require.Nil(t, err)
+		require.True(t, ok)
+	}
+}
+
+func TestUnmarshalPoints(t *testing.T) {
+	// The identity and points outside of the subgroups are rejected
+	identity := make([]byte, RawSignatureLength)
+	identity[0] = 0xc0
+	_, err := UnmarshalPublicKey(identity[:PublicKeyLength])
+	require.NotNil(t, err)
+
+	g1 := bls12381.NewG1()
+	notInG1 := g1.ToCompressed(g1.One())
+	notInG1[PublicKeyLength-1] ^= 0x01
+	_, err = UnmarshalPublicKey(notInG1)
+	require.NotNil(t, err)
+
+	g2 := bls12381.NewG2()
+	notInG2 := g2.ToCompressed(g2.One())
+	notInG2[RawSignatureLength-1] ^= 0x01
+	priv, err := New()
+	require.Nil(t, err)
+	for _, sig := range [][]byte{notInG2, identity, identity[:RawSignatureLength-1]} {
+		ok, err := priv.PublicKey().Verify(make([]byte, 32), sig)
+		require.NotNil(t, err)
+		require.False(t, ok)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	digest := sha256.Sum256([]byte("bitxhub"))
+
+	priv, err := New()
+	require.Nil(t, err)
+	require.Equal(t, crypto.KeyType(crypto.BLS12_381), priv.Type())
+
+	sig, err := priv.Sign(digest[:])
+	require.Nil(t, err)
+	require.Equal(t, SignatureLength, len(sig))
+
+	ok, err := priv.PublicKey().Verify(digest[:], sig)
+	require.Nil(t, err)
+	require.True(t, ok)
+	ok, err = priv.PublicKey().Verify(digest[:], sig[PublicKeyLength:])
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	wrong := sha256.Sum256([]byte("bitxhub1"))
+	ok, err = priv.PublicKey().Verify(wrong[:], sig)
+	require.NotNil(t, err)
+	require.False(t, ok)
+
+	pub, err := SigToPub(sig)
+	require.Nil(t, err)
+	require.Equal(t, priv.PublicKey(), pub)
+
+	raw, err := priv.Bytes()
+	require.Nil(t, err)
+	restored, err := UnmarshalPrivateKey(raw)
+	require.Nil(t, err)
+	require.Equal(t, priv.sk, restored.sk)
+	pubBytes, err := pub.Bytes()
+	require.Nil(t, err)
+	restoredPub, err := UnmarshalPublicKey(pubBytes)
+	require.Nil(t, err)
+	require.Equal(t, pub, restoredPub)
+
+	_, err = UnmarshalPrivateKey(make([]byte, PrivateKeyLength))
+	require.NotNil(t, err)
+	_, err = UnmarshalPrivateKey(r.Bytes())
+	require.NotNil(t, err)
+}
+
+func TestAggregate(t *testing.T) {
+	digest := sha256.Sum256([]byte("block 1"))
+
+	var (
+		privs = make([]*PrivateKey, 3)
+		pubs  = make([]*PublicKey, 3)
+		sigs  = make([][]byte, 3)
+	)
+	for i := range privs {
+		priv, err := New()
+		require.Nil(t, err)
+		proof, err := priv.ProvePossession()
+		require.Nil(t, err)
+		ok, err := priv.pub.VerifyPossession(proof)
+		require.Nil(t, err)
+		require.True(t, ok)
+
+		privs[i] = priv
+		pubs[i] = priv.pub
+		sigs[i], err = priv.Sign(digest[:])
+		require.Nil(t, err)
+	}
+
+	// A proof doesn't carry over to another key
+	proof, err := privs[0].ProvePossession()
+	require.Nil(t, err)
+	ok, err := pubs[1].VerifyPossession(proof)
+	require.NotNil(t, err)
+	require.False(t, ok)
+
+	agg, err := AggregateSignatures(sigs)
+	require.Nil(t, err)
+	require.Equal(t, RawSignatureLength, len(agg))
+	ok, err = FastAggregateVerify(pubs, digest[:], agg)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	ok, err = FastAggregateVerify(pubs[:2], digest[:], agg)
+	require.NotNil(t, err)
+	require.False(t, ok)
+
+	// Signatures of different digests
+	digests := make([][]byte, 3)
+	for i, priv := range privs {
+		d := sha256.Sum256([]byte{byte(i)})
+		digests[i] = d[:]
+		sigs[i], err = priv.Sign(digests[i])
+		require.Nil(t, err)
+	}
+	agg, err = AggregateSignatures(sigs)
+	require.Nil(t, err)
+	ok, err = AggregateVerify(pubs, digests, agg)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	digests[0], digests[1] = digests[1], digests[0]
+	ok, err = AggregateVerify(pubs, digests, agg)
+	require.NotNil(t, err)
+	require.False(t, ok)
+}
+
+func TestBatchVerify(t *testing.T) {
+	var (
+		pubs    = make([]*PublicKey, 3)
+		digests = make([][]byte, 3)
+		sigs    = make([][]byte, 3)
+	)
+	for i := range pubs {
+		priv, err := New()
+		require.Nil(t, err)
+		d := sha256.Sum256([]byte{byte(i)})
+		pubs[i] = priv.pub
+		digests[i] = d[:]
+		sigs[i], err = priv.Sign(digests[i])
+		require.Nil(t, err)
+	}
+
+	ok, err := BatchVerify(pubs, digests, sigs)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	sigs[0], sigs[1] = sigs[1], sigs[0]
+	ok, err = BatchVerify(pubs, digests, sigs)
+	require.NotNil(t, err)
+	require.False(t, ok)
+}
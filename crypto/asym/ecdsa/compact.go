@@ -0,0 +1,213 @@
+package ecdsa
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+)
+
+// SigEncoding is the encoding of NIST curve signatures produced by Sign
+type SigEncoding int
+
+const (
+	// EncodingASN1 is the ASN.1 Sig with the PKIX public key embedded
+	EncodingASN1 SigEncoding = iota
+	// EncodingCompact is the fixed width [0x01 || R || S || V], the public
+	// key is recovered with the recovery id V
+	EncodingCompact
+	// EncodingRaw is the fixed width [0x02 || R || S], the public key must be
+	// known by the verifier
+	EncodingRaw
+)
+
+// The fixed width encodings start with their own prefix byte, which an ASN.1
+// signature can't start with as it is a DER sequence starting with 0x30. So
+// the encoding of a signature, also behind the key type of SignWithType, is
+// told by its first byte.
+const (
+	compactPrefix byte = 0x01
+	rawPrefix     byte = 0x02
+)
+
+// SetEncoding sets the encoding of NIST curve signatures produced by Sign.
+// Secp256k1 signatures always have the [R || S || V] format.
+func (priv *PrivateKey) SetEncoding(encoding SigEncoding) error {
+	switch encoding {
+	case EncodingASN1, EncodingCompact, EncodingRaw:
+		priv.encoding = encoding
+		return nil
+	default:
+		return fmt.Errorf("unsupported ecdsa signature encoding %d", encoding)
+	}
+}
+
+func nistCurve(opt crypto.KeyType) (elliptic.Curve, error) {
+	switch opt {
+	case crypto.ECDSA_P256:
+		return elliptic.P256(), nil
+	case crypto.ECDSA_P384:
+		return elliptic.P384(), nil
+	case crypto.ECDSA_P521:
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("not supported curve")
+	}
+}
+
+// scalarSize is the width of R and S in the fixed width encodings
+func scalarSize(curve elliptic.Curve) int {
+	return (curve.Params().N.BitLen() + 7) / 8
+}
+
+// encodeSig encodes a signature of a NIST curve key.
+func (priv *PrivateKey) encodeSig(digest []byte, r, s *big.Int) ([]byte, error) {
+	if priv.encoding == EncodingASN1 {
+		pubBytes, err := priv.PublicKey().Bytes()
+		if err != nil {
+			return nil, err
+		}
+
+		return asn1.Marshal(Sig{Pub: pubBytes, R: r, S: s})
+	}
+
+	size := scalarSize(priv.K.Curve)
+	sig := make([]byte, 1+2*size, 2*size+2)
+	copy(sig[1:1+size], PaddedBigBytes(r, size))
+	copy(sig[1+size:], PaddedBigBytes(s, size))
+	if priv.encoding == EncodingRaw {
+		sig[0] = rawPrefix
+		return sig, nil
+	}
+	sig[0] = compactPrefix
+
+	for v := byte(0); v < 2; v++ {
+		pub, err := recoverNIST(priv.K.Curve, digest, r, s, v)
+		if err == nil && pub.X.Cmp(priv.K.X) == 0 && pub.Y.Cmp(priv.K.Y) == 0 {
+			return append(sig, v), nil
+		}
+	}
+	return nil, fmt.Errorf("can't compute the recovery id of the signature")
+}
+
+// RecoverCompact returns the public key that created a signature in the
+// compact [0x01 || R || S || V] encoding.
+func RecoverCompact(opt crypto.KeyType, digest, sig []byte) (*PublicKey, error) {
+	curve, err := nistCurve(opt)
+	if err != nil {
+		return nil, err
+	}
+	r, s, err := fixedWidthSig(curve, sig)
+	if err != nil {
+		return nil, err
+	}
+	if sig[0] != compactPrefix {
+		return nil, fmt.Errorf("not a compact signature")
+	}
+
+	pub, err := recoverNIST(curve, digest, r, s, sig[len(sig)-1])
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKey{K: pub}, nil
+}
+
+// fixedWidthSig returns R and S of a signature in the compact or raw
+// encoding.
+func fixedWidthSig(curve elliptic.Curve, sig []byte) (*big.Int, *big.Int, error) {
+	size := scalarSize(curve)
+	expected := 0
+	if len(sig) != 0 {
+		switch sig[0] {
+		case compactPrefix:
+			expected = 2*size + 2
+		case rawPrefix:
+			expected = 2*size + 1
+		}
+	}
+	if expected == 0 || len(sig) != expected {
+		return nil, nil, fmt.Errorf("invalid fixed width signature")
+	}
+
+	r := new(big.Int).SetBytes(sig[1 : 1+size])
+	s := new(big.Int).SetBytes(sig[1+size : 1+2*size])
+	return r, s, nil
+}
+
+// recoverNIST computes the public key r^-1 * (s*R - e*G), where the y
+// coordinate of R has the parity v. R.x is assumed to be r, as r + n
+// exceeds the field size with negligible probability.
+func recoverNIST(curve elliptic.Curve, digest []byte, r, s *big.Int, v byte) (*ecdsa.PublicKey, error) {
+	params := curve.Params()
+	n, p := params.N, params.P
+	if r.Sign() <= 0 || r.Cmp(n) >= 0 || s.Sign() <= 0 || s.Cmp(n) >= 0 || v > 1 {
+		return nil, fmt.Errorf("invalid signature")
+	}
+
+	// y^2 = x^3 - 3x + b
+	x := new(big.Int).Set(r)
+	y2 := new(big.Int).Exp(x, big.NewInt(3), p)
+	threeX := new(big.Int).Lsh(x, 1)
+	threeX.Add(threeX, x)
+	y2.Sub(y2, threeX)
+	y2.Add(y2, params.B)
+	y2.Mod(y2, p)
+	y := new(big.Int).ModSqrt(y2, p)
+	if y == nil {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	if y.Bit(0) != uint(v) {
+		y.Sub(p, y)
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+
+	size := scalarSize(curve)
+	e := bits2int(digest, n.BitLen())
+	e.Mod(e, n)
+	sx, sy := curve.ScalarMult(x, y, PaddedBigBytes(s, size))
+	ex, ey := curve.ScalarBaseMult(PaddedBigBytes(e, size))
+	if ey.Sign() != 0 {
+		ey.Sub(p, ey)
+	}
+	qx, qy := curve.Add(sx, sy, ex, ey)
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	rInv := new(big.Int).ModInverse(r, n)
+	qx, qy = curve.ScalarMult(qx, qy, PaddedBigBytes(rInv, size))
+
+	return &ecdsa.PublicKey{Curve: curve, X: qx, Y: qy}, nil
+}
+
+// SigToPubKey returns the public key of a NIST curve signature, which is
+// embedded in the ASN.1 encoding or recovered from the compact encoding.
+// Raw signatures don't carry the public key.
+func SigToPubKey(opt crypto.KeyType, digest, sig []byte) (*PublicKey, error) {
+	if _, err := nistCurve(opt); err != nil {
+		return nil, err
+	}
+
+	if len(sig) != 0 {
+		switch sig[0] {
+		case compactPrefix:
+			return RecoverCompact(opt, digest, sig)
+		case rawPrefix:
+			return nil, fmt.Errorf("raw signature doesn't carry the public key")
+		}
+	}
+
+	sigStruct := &Sig{}
+	if _, err := asn1.Unmarshal(sig, sigStruct); err != nil {
+		return nil, err
+	}
+	pub, err := UnmarshalPublicKey(sigStruct.Pub, opt)
+	if err != nil {
+		return nil, err
+	}
+	return pub.(*PublicKey), nil
+}
@@ -5,7 +5,6 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
-	"encoding/asn1"
 	"fmt"
 	"math/big"
 
@@ -18,8 +17,10 @@ var _ crypto.PublicKey = (*PublicKey)(nil)
 // PrivateKey ECDSA private key.
 // never new(PrivateKey), use NewPrivateKey()
 type PrivateKey struct {
-	curve crypto.KeyType
-	K     *ecdsa.PrivateKey
+	curve         crypto.KeyType
+	K             *ecdsa.PrivateKey
+	deterministic bool
+	encoding      SigEncoding
 }
 
 // ECDSASig holds the r and s values of an ECDSA signature
@@ -98,21 +99,31 @@ func (priv *PrivateKey) PublicKey() crypto.PublicKey {
 	return &PublicKey{K: &priv.K.PublicKey}
 }
 
+// SetDeterministic makes Sign use RFC 6979 nonces and low S values for NIST
+// curves. Secp256k1 signatures are always deterministic.
+func (priv *PrivateKey) SetDeterministic(deterministic bool) {
+	priv.deterministic = deterministic
+}
+
 func (priv *PrivateKey) Sign(digest []byte) ([]byte, error) {
 	if priv.curve == crypto.Secp256k1 {
 		return Sign(digest, priv.K)
 	}
-	r, s, err := ecdsa.Sign(rand.Reader, priv.K, digest[:])
-	if err != nil {
-		return nil, err
-	}
 
-	pubBytes, err := priv.PublicKey().Bytes()
+	var (
+		r, s *big.Int
+		err  error
+	)
+	if priv.deterministic {
+		r, s, err = SignRFC6979(priv.K, digest)
+	} else {
+		r, s, err = ecdsa.Sign(rand.Reader, priv.K, digest[:])
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return asn1.Marshal(Sig{Pub: pubBytes, R: r, S: s})
+	return priv.encodeSig(digest, r, s)
 }
 
 func (priv *PrivateKey) Type() crypto.KeyType {
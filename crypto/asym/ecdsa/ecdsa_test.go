@@ -1,8 +1,11 @@
 package ecdsa
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/sha256"
 	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/meshplus/bitxhub-kit/crypto"
@@ -25,6 +28,22 @@ func TestSignR1(t *testing.T) {
 	require.True(t, b)
 }
 
+func TestSignSecp256k1(t *testing.T) {
+	h := sha256.Sum256(msg)
+	priv, err := New(crypto.Secp256k1)
+	require.Nil(t, err)
+	sign, err := priv.Sign(h[:])
+	require.Nil(t, err)
+	b, err := priv.PublicKey().Verify(h[:], sign)
+	require.Nil(t, err)
+	require.True(t, b)
+
+	h[0] ^= 1
+	b, err = priv.PublicKey().Verify(h[:], sign)
+	require.NotNil(t, err)
+	require.False(t, b)
+}
+
 func TestGenerateKey(t *testing.T) {
 	// Secp256k1 marshal not supported yet
 	// keyK1, err := GenerateKey(Secp256k1)
@@ -66,3 +85,91 @@ func TestPublicKey(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, addr1, addr2)
 }
+
+// Test vector of RFC 6979 A.2.5, P-256 with SHA-256 and message "sample"
+func TestSignRFC6979(t *testing.T) {
+	d, _ := new(big.Int).SetString("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721", 16)
+	r, _ := new(big.Int).SetString("EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716", 16)
+	s, _ := new(big.Int).SetString("F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8", 16)
+
+	priv := &ecdsa.PrivateKey{D: d}
+	priv.Curve = elliptic.P256()
+	priv.X, priv.Y = priv.Curve.ScalarBaseMult(d.Bytes())
+
+	h := sha256.Sum256([]byte("sample"))
+	sigR, sigS, err := SignRFC6979(priv, h[:])
+	require.Nil(t, err)
+	require.Equal(t, r, sigR)
+	// s is above half the order and gets normalised
+	require.Equal(t, new(big.Int).Sub(priv.Params().N, s), sigS)
+	require.True(t, ecdsa.Verify(&priv.PublicKey, h[:], sigR, sigS))
+
+	for _, opt := range []crypto.KeyType{crypto.ECDSA_P256, crypto.ECDSA_P384, crypto.ECDSA_P521} {
+		key, err := New(opt)
+		require.Nil(t, err)
+		key.(*PrivateKey).SetDeterministic(true)
+
+		sig1, err := key.Sign(h[:])
+		require.Nil(t, err)
+		sig2, err := key.Sign(h[:])
+		require.Nil(t, err)
+		require.Equal(t, sig1, sig2)
+
+		b, err := key.PublicKey().Verify(h[:], sig1)
+		require.Nil(t, err)
+		require.True(t, b)
+	}
+}
+
+func TestSigEncoding(t *testing.T) {
+	h := sha256.Sum256(msg)
+
+	for _, opt := range []crypto.KeyType{crypto.ECDSA_P256, crypto.ECDSA_P384, crypto.ECDSA_P521} {
+		key, err := New(opt)
+		require.Nil(t, err)
+		priv := key.(*PrivateKey)
+		pub := priv.PublicKey()
+		size := (priv.K.Params().N.BitLen() + 7) / 8
+
+		require.Nil(t, priv.SetEncoding(EncodingCompact))
+		compact, err := priv.Sign(h[:])
+		require.Nil(t, err)
+		require.Equal(t, 2*size+2, len(compact))
+		b, err := pub.Verify(h[:], compact)
+		require.Nil(t, err)
+		require.True(t, b)
+
+		recovered, err := RecoverCompact(opt, h[:], compact)
+		require.Nil(t, err)
+		require.Equal(t, pub, recovered)
+		recovered, err = SigToPubKey(opt, h[:], compact)
+		require.Nil(t, err)
+		require.Equal(t, pub, recovered)
+
+		require.Nil(t, priv.SetEncoding(EncodingRaw))
+		raw, err := priv.Sign(h[:])
+		require.Nil(t, err)
+		require.Equal(t, 2*size+1, len(raw))
+		b, err = pub.Verify(h[:], raw)
+		require.Nil(t, err)
+		require.True(t, b)
+		_, err = SigToPubKey(opt, h[:], raw)
+		require.NotNil(t, err)
+
+		wrong := sha256.Sum256([]byte("hypercha1n"))
+		b, err = pub.Verify(wrong[:], raw)
+		require.NotNil(t, err)
+		require.False(t, b)
+
+		// The prefix tells the encoding, not the length
+		mislabeled := append([]byte{rawPrefix}, compact[1:]...)
+		_, err = pub.Verify(h[:], mislabeled)
+		require.NotNil(t, err)
+		_, err = pub.Verify(h[:], raw[1:])
+		require.NotNil(t, err)
+		_, err = RecoverCompact(opt, h[:], raw)
+		require.NotNil(t, err)
+
+		require.NotNil(t, priv.SetEncoding(SigEncoding(3)))
+	}
+}
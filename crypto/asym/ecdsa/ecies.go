@@ -0,0 +1,122 @@
+package ecdsa
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/ecdh"
+	"github.com/meshplus/bitxhub-kit/crypto/sym"
+	"golang.org/x/crypto/hkdf"
+)
+
+var _ crypto.Encrypter = (*PublicKey)(nil)
+var _ crypto.Decrypter = (*PrivateKey)(nil)
+
+var eciesInfo = []byte("bitxhub-ecies")
+
+const eciesMacLen = sha256.Size
+
+// Encrypt encrypts plain text to the owner of the public key with ECIES.
+//
+// An ephemeral key agrees on a secret with the public key, HKDF-SHA256 derives
+// an AES-256 key and an HMAC-SHA256 key from it. The output is
+// ephemeral public key || AES-256-CBC cipher text || mac.
+func (pub *PublicKey) Encrypt(plain []byte) ([]byte, error) {
+	if pub.K == nil {
+		return nil, fmt.Errorf("ECDSAPublicKey.K is nil, please invoke FromBytes()")
+	}
+	curve := pub.K.Curve
+
+	ephemeral, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	ephemeralKey, err := NewWithCryptoKey(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeralPub := elliptic.Marshal(curve, ephemeral.X, ephemeral.Y)
+	encKey, macKey, err := eciesKeys(curve, ephemeralKey, elliptic.Marshal(curve, pub.K.X, pub.K.Y), ephemeralPub)
+	if err != nil {
+		return nil, err
+	}
+
+	aesKey, err := sym.GenerateSymKey(crypto.AES, encKey)
+	if err != nil {
+		return nil, err
+	}
+	crypted, err := aesKey.Encrypt(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(ephemeralPub)+len(crypted)+eciesMacLen)
+	out = append(out, ephemeralPub...)
+	out = append(out, crypted...)
+	return append(out, eciesMac(macKey, out)...), nil
+}
+
+// Decrypt decrypts the output of PublicKey.Encrypt.
+func (priv *PrivateKey) Decrypt(crypted []byte) ([]byte, error) {
+	if priv.K == nil {
+		return nil, fmt.Errorf("ECDSAPrivateKey.K is nil, please invoke FromBytes()")
+	}
+	curve := priv.K.Curve
+
+	pubLen := 1 + 2*((curve.Params().BitSize+7)/8)
+	if len(crypted) <= pubLen+eciesMacLen {
+		return nil, fmt.Errorf("invalid ecies cipher text")
+	}
+	ephemeralPub := crypted[:pubLen]
+	body := crypted[:len(crypted)-eciesMacLen]
+	mac := crypted[len(crypted)-eciesMacLen:]
+
+	encKey, macKey, err := eciesKeys(curve, priv, ephemeralPub, ephemeralPub)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(eciesMac(macKey, body), mac) {
+		return nil, fmt.Errorf("invalid ecies message authentication code")
+	}
+
+	aesKey, err := sym.GenerateSymKey(crypto.AES, encKey)
+	if err != nil {
+		return nil, err
+	}
+	return aesKey.Decrypt(body[pubLen:])
+}
+
+// eciesKeys agrees on a secret between priv and peerPub and derives the
+// encryption and mac keys from it, salted with the ephemeral public key.
+func eciesKeys(curve elliptic.Curve, priv crypto.PrivateKey, peerPub, ephemeralPub []byte) ([]byte, []byte, error) {
+	ke, err := ecdh.NewEllipticECDH(curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := ke.ComputeSecret(priv, peerPub)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The KDF input has the fixed length of the curve size
+	secret := make([]byte, (curve.Params().BitSize+7)/8)
+	copy(secret[len(secret)-len(raw):], raw)
+
+	keys := make([]byte, 64)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, ephemeralPub, eciesInfo), keys); err != nil {
+		return nil, nil, err
+	}
+	return keys[:32], keys[32:], nil
+}
+
+func eciesMac(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,46 @@
+package ecdsa
+
+import (
+	"testing"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestECIES(t *testing.T) {
+	testECIES(t, crypto.Secp256k1)
+	testECIES(t, crypto.ECDSA_P256)
+	testECIES(t, crypto.ECDSA_P384)
+	testECIES(t, crypto.ECDSA_P521)
+}
+
+func testECIES(t *testing.T, opt crypto.KeyType) {
+	priv, err := New(opt)
+	require.Nil(t, err)
+	other, err := New(opt)
+	require.Nil(t, err)
+
+	plain := []byte("interchain payload")
+	enc, ok := priv.PublicKey().(crypto.Encrypter)
+	require.True(t, ok)
+	crypted, err := enc.Encrypt(plain)
+	require.Nil(t, err)
+
+	dec, ok := priv.(crypto.Decrypter)
+	require.True(t, ok)
+	decrypted, err := dec.Decrypt(crypted)
+	require.Nil(t, err)
+	require.Equal(t, plain, decrypted)
+
+	// Only the owner of the public key can decrypt
+	_, err = other.(crypto.Decrypter).Decrypt(crypted)
+	require.NotNil(t, err)
+
+	// Tampering is detected by the mac
+	crypted[len(crypted)-40] ^= 0x01
+	_, err = dec.Decrypt(crypted)
+	require.NotNil(t, err)
+
+	_, err = dec.Decrypt(crypted[:10])
+	require.NotNil(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/x509"
 	"encoding/asn1"
 	"fmt"
+	"math/big"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/meshplus/bitxhub-kit/crypto"
@@ -100,12 +101,30 @@ func (pub *PublicKey) Verify(digest []byte, sig []byte) (bool, error) {
 		return false, fmt.Errorf("nil signature")
 	}
 
-	sigStruct := &Sig{}
-	if _, err := asn1.Unmarshal(sig, sigStruct); err != nil {
-		return false, err
+	// secp256k1 keys sign in the recoverable [R || S || V] format
+	if pub.Type() == crypto.Secp256k1 && len(sig) == SignatureLength {
+		if !VerifySignature(FromECDSAPub(pub.K), digest, sig[:RecoveryIDOffset]) {
+			return false, fmt.Errorf("invalid signature")
+		}
+		return true, nil
+	}
+
+	var r, s *big.Int
+	if curve, err := nistCurve(pub.Type()); err == nil && len(sig) != 0 && (sig[0] == compactPrefix || sig[0] == rawPrefix) {
+		// The fixed width encodings, the recovery id is not needed here
+		r, s, err = fixedWidthSig(curve, sig)
+		if err != nil {
+			return false, err
+		}
+	} else {
+		sigStruct := &Sig{}
+		if _, err := asn1.Unmarshal(sig, sigStruct); err != nil {
+			return false, err
+		}
+		r, s = sigStruct.R, sigStruct.S
 	}
 
-	if !ecdsa.Verify(pub.K, digest, sigStruct.R, sigStruct.S) {
+	if !ecdsa.Verify(pub.K, digest, r, s) {
 		return false, fmt.Errorf("invalid signature")
 	}
 
@@ -0,0 +1,98 @@
+package ecdsa
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"math/big"
+)
+
+// SignRFC6979 signs digest with the nonce derived from the key and digest as
+// described in RFC 6979, so that signing is reproducible. The HMAC uses
+// SHA-256, SHA-384 or SHA-512 according to the size of the curve. S is
+// normalised to the lower half of the curve order.
+func SignRFC6979(priv *ecdsa.PrivateKey, digest []byte) (r, s *big.Int, err error) {
+	if priv == nil || priv.D == nil {
+		return nil, nil, fmt.Errorf("empty ecdsa private key")
+	}
+
+	curve := priv.Curve
+	n := curve.Params().N
+	qlen := n.BitLen()
+	rlen := (qlen + 7) / 8
+
+	var newHash func() hash.Hash
+	switch {
+	case qlen <= 256:
+		newHash = sha256.New
+	case qlen <= 384:
+		newHash = sha512.New384
+	default:
+		newHash = sha512.New
+	}
+
+	e := bits2int(digest, qlen)
+	h1 := new(big.Int).Mod(e, n)
+	x := PaddedBigBytes(priv.D, rlen)
+	defer ZeroBytes(x)
+
+	// Step b to g of RFC 6979 section 3.2
+	hlen := newHash().Size()
+	v := make([]byte, hlen)
+	for i := range v {
+		v[i] = 0x01
+	}
+	k := make([]byte, hlen)
+	mac := func(key []byte, data ...[]byte) []byte {
+		m := hmac.New(newHash, key)
+		for _, d := range data {
+			m.Write(d)
+		}
+		return m.Sum(nil)
+	}
+	k = mac(k, v, []byte{0x00}, x, PaddedBigBytes(h1, rlen))
+	v = mac(k, v)
+	k = mac(k, v, []byte{0x01}, x, PaddedBigBytes(h1, rlen))
+	v = mac(k, v)
+
+	for {
+		var t []byte
+		for len(t) < rlen {
+			v = mac(k, v)
+			t = append(t, v...)
+		}
+
+		nonce := bits2int(t[:rlen], qlen)
+		if nonce.Sign() > 0 && nonce.Cmp(n) < 0 {
+			kx, _ := curve.ScalarBaseMult(PaddedBigBytes(nonce, rlen))
+			r = kx.Mod(kx, n)
+			if r.Sign() != 0 {
+				s = new(big.Int).Mul(r, priv.D)
+				s.Add(s, e)
+				s.Mul(s, nonce.ModInverse(nonce, n))
+				s.Mod(s, n)
+				if s.Sign() != 0 {
+					if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+						s.Sub(n, s)
+					}
+					return r, s, nil
+				}
+			}
+		}
+
+		k = mac(k, v, []byte{0x00})
+		v = mac(k, v)
+	}
+}
+
+// bits2int converts a bit string to an integer of at most qlen bits.
+func bits2int(b []byte, qlen int) *big.Int {
+	ret := new(big.Int).SetBytes(b)
+	if excess := len(b)*8 - qlen; excess > 0 {
+		ret.Rsh(ret, uint(excess))
+	}
+	return ret
+}
@@ -30,7 +30,6 @@ import (
 	"math/big"
 	"os"
 
-	"github.com/meshplus/bitxhub-kit/crypto/asym/ecdsa/secp256k1"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -95,7 +94,7 @@ func ToECDSAUnsafe(d []byte) *ecdsa.PrivateKey {
 // it can also accept legacy encodings (0 prefixes).
 func toECDSA(d []byte, strict bool) (*ecdsa.PrivateKey, error) {
 	priv := new(ecdsa.PrivateKey)
-	priv.PublicKey.Curve = secp256k1.S256()
+	priv.PublicKey.Curve = S256()
 	if strict && 8*len(d) != priv.Params().BitSize {
 		return nil, fmt.Errorf("invalid length, need %d bits", priv.Params().BitSize)
 	}
@@ -151,18 +150,18 @@ func ReadBits(bigint *big.Int, buf []byte) {
 
 // UnmarshalPubkey converts bytes to a secp256k1 public key.
 func UnmarshalPubkey(pub []byte) (*ecdsa.PublicKey, error) {
-	x, y := elliptic.Unmarshal(secp256k1.S256(), pub)
+	x, y := elliptic.Unmarshal(S256(), pub)
 	if x == nil {
 		return nil, errInvalidPubkey
 	}
-	return &ecdsa.PublicKey{Curve: secp256k1.S256(), X: x, Y: y}, nil
+	return &ecdsa.PublicKey{Curve: S256(), X: x, Y: y}, nil
 }
 
 func FromECDSAPub(pub *ecdsa.PublicKey) []byte {
 	if pub == nil || pub.X == nil || pub.Y == nil {
 		return nil
 	}
-	return elliptic.Marshal(secp256k1.S256(), pub.X, pub.Y)
+	return elliptic.Marshal(S256(), pub.X, pub.Y)
 }
 
 // HexToECDSA parses a secp256k1 private key.
@@ -240,10 +239,12 @@ func SaveECDSA(file string, key *ecdsa.PrivateKey) error {
 
 // GenerateKey generates a new private key.
 func GenerateKey() (*ecdsa.PrivateKey, error) {
-	return ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+	return ecdsa.GenerateKey(S256(), rand.Reader)
 }
 
-func zeroBytes(bytes []byte) {
+// ZeroBytes overwrites the bytes, for instance of a secret that is no longer
+// needed, with zeros.
+func ZeroBytes(bytes []byte) {
 	for i := range bytes {
 		bytes[i] = 0
 	}
@@ -263,3 +264,43 @@ func ValidateSignatureValues(v byte, r, s *big.Int, homestead bool) bool {
 	// Frontier: allow s to be in full N range
 	return r.Cmp(secp256k1N) < 0 && s.Cmp(secp256k1N) < 0 && (v == 0 || v == 1)
 }
+
+// SigToPub returns the public key that created the given signature.
+func SigToPub(hash, sig []byte) (*ecdsa.PublicKey, error) {
+	s, err := Ecrecover(hash, sig)
+	if err != nil {
+		return nil, err
+	}
+
+	x, y := elliptic.Unmarshal(S256(), s)
+	return &ecdsa.PublicKey{Curve: S256(), X: x, Y: y}, nil
+}
+
+func RecoverPlain(hash []byte, R, S, Vb *big.Int, homestead bool) ([]byte, error) {
+	if R == nil || S == nil || Vb == nil {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	if Vb.BitLen() > 8 {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	V := byte(Vb.Uint64() - 27)
+	if !ValidateSignatureValues(V, R, S, homestead) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	// encode the signature in uncompressed format
+	r, s := R.Bytes(), S.Bytes()
+	sig := make([]byte, SignatureLength)
+	copy(sig[32-len(r):32], r)
+	copy(sig[64-len(s):64], s)
+	sig[64] = V
+	// recover the public key from the signature
+	pub, err := Ecrecover(hash, sig)
+	if err != nil {
+		return nil, err
+	}
+	if len(pub) == 0 || pub[0] != 4 {
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	return Keccak256(pub[1:])[12:], nil
+}
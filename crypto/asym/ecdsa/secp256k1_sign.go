@@ -23,7 +23,6 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"fmt"
-	"math/big"
 
 	"github.com/meshplus/bitxhub-kit/crypto/asym/ecdsa/secp256k1"
 )
@@ -33,17 +32,6 @@ func Ecrecover(hash, sig []byte) ([]byte, error) {
 	return secp256k1.RecoverPubkey(hash, sig)
 }
 
-// SigToPub returns the public key that created the given signature.
-func SigToPub(hash, sig []byte) (*ecdsa.PublicKey, error) {
-	s, err := Ecrecover(hash, sig)
-	if err != nil {
-		return nil, err
-	}
-
-	x, y := elliptic.Unmarshal(S256(), s)
-	return &ecdsa.PublicKey{Curve: S256(), X: x, Y: y}, nil
-}
-
 // Sign calculates an ECDSA signature.
 //
 // This function is susceptible to chosen plaintext attacks that can leak
@@ -57,7 +45,7 @@ func Sign(digestHash []byte, prv *ecdsa.PrivateKey) (sig []byte, err error) {
 		return nil, fmt.Errorf("hash is required to be exactly %d bytes (%d)", DigestLength, len(digestHash))
 	}
 	seckey := PaddedBigBytes(prv.D, prv.Params().BitSize/8)
-	defer zeroBytes(seckey)
+	defer ZeroBytes(seckey)
 	return secp256k1.Sign(digestHash, seckey)
 }
 
@@ -86,32 +74,3 @@ func CompressPubkey(pubkey *ecdsa.PublicKey) []byte {
 func S256() elliptic.Curve {
 	return secp256k1.S256()
 }
-
-func RecoverPlain(hash []byte, R, S, Vb *big.Int, homestead bool) ([]byte, error) {
-	if R == nil || S == nil || Vb == nil {
-		return nil, fmt.Errorf("invalid signature")
-	}
-	if Vb.BitLen() > 8 {
-		return nil, fmt.Errorf("invalid signature")
-	}
-	V := byte(Vb.Uint64() - 27)
-	if !ValidateSignatureValues(V, R, S, homestead) {
-		return nil, fmt.Errorf("invalid signature")
-	}
-	// encode the signature in uncompressed format
-	r, s := R.Bytes(), S.Bytes()
-	sig := make([]byte, SignatureLength)
-	copy(sig[32-len(r):32], r)
-	copy(sig[64-len(s):64], s)
-	sig[64] = V
-	// recover the public key from the signature
-	pub, err := Ecrecover(hash, sig)
-	if err != nil {
-		return nil, err
-	}
-	if len(pub) == 0 || pub[0] != 4 {
-		return nil, fmt.Errorf("invalid public key")
-	}
-
-	return Keccak256(pub[1:])[12:], nil
-}
@@ -0,0 +1,80 @@
+package ecdsa
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// secp256k1Vectors are shared by the cgo and the pure Go backend, run the
+// tests with CGO_ENABLED=0 as well to check that both produce the same bytes.
+var secp256k1Vectors = []struct {
+	key          string
+	digest       string
+	compressed   string
+	uncompressed string
+	sig          string
+}{
+	{
+		key:          "0000000000000000000000000000000000000000000000000000000000000001",
+		digest:       "9a212dfbf37014fb34fad4192d77e56f49b4a87442688aa034aec9220951c113",
+		compressed:   "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		uncompressed: "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8",
+		sig:          "3034085b708459b1b5f05d8cccdd262569251c135aa6484d955d8d9c56ba419515961873efc67ada5388ab28a09bd2411e614ac734fc1a6205b54ee742d4f08e00",
+	},
+	{
+		key:          "289c2857d4598e37fb9647507e47a309d6133539bf21a8b9cb6df88fd5232032",
+		digest:       "a9cc0348859e5b5ff83e0f9faf57175c5f5e2b8e523b852ec60d690bbf9e41ae",
+		compressed:   "037db227d7094ce215c3a0f57e1bcc732551fe351f94249471934567e0f5dc1bf7",
+		uncompressed: "047db227d7094ce215c3a0f57e1bcc732551fe351f94249471934567e0f5dc1bf795962b8cccb87a2eb56b29fbe37d614e2f4c3c45b789ae4f1f51f4cb21972ffd",
+		sig:          "2a9bb601b032213c3bb7287f0cbd170756f60b8f4c1f3e6e56a6c5a5ae4452c811b61ce770ebb31695152dd9bbe10cc708ddf8be135d63a302c1fc588cc1be6b01",
+	},
+	{
+		key:          "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+		digest:       "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		compressed:   "0379be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		uncompressed: "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798b7c52588d95c3b9aa25b0403f1eef75702e84bb7597aabe663b82f6f04ef2777",
+		sig:          "ea045bf0962ecc4d5aa84c8e716c87c9d5f49fba8e1ff0300ab2631de3d83b4351270ec8105346fddf35da5958d99ff55a0c0f720d6ae7f3e3eadd40a9ccfe0e01",
+	},
+}
+
+func TestSecp256k1Vectors(t *testing.T) {
+	for _, v := range secp256k1Vectors {
+		priv, err := HexToECDSA(v.key)
+		require.Nil(t, err)
+		digest, _ := hex.DecodeString(v.digest)
+
+		compressed := CompressPubkey(&priv.PublicKey)
+		require.Equal(t, v.compressed, hex.EncodeToString(compressed))
+		pub, err := DecompressPubkey(compressed)
+		require.Nil(t, err)
+		require.Equal(t, v.uncompressed, hex.EncodeToString(FromECDSAPub(pub)))
+
+		sig, err := Sign(digest, priv)
+		require.Nil(t, err)
+		require.Equal(t, v.sig, hex.EncodeToString(sig))
+
+		recovered, err := Ecrecover(digest, sig)
+		require.Nil(t, err)
+		require.Equal(t, v.uncompressed, hex.EncodeToString(recovered))
+
+		require.True(t, VerifySignature(compressed, digest, sig[:64]))
+		require.True(t, VerifySignature(recovered, digest, sig[:64]))
+		require.False(t, VerifySignature(compressed, digest, sig))
+
+		// The malleable high S form is rejected
+		s := new(big.Int).Sub(secp256k1N, new(big.Int).SetBytes(sig[32:64]))
+		highS := append(append([]byte{}, sig[:32]...), PaddedBigBytes(s, 32)...)
+		require.False(t, VerifySignature(compressed, digest, highS))
+
+		digest[0] ^= 1
+		require.False(t, VerifySignature(compressed, digest, sig[:64]))
+	}
+
+	_, err := DecompressPubkey(make([]byte, 33))
+	require.NotNil(t, err)
+	_, err = Ecrecover(make([]byte, 32), make([]byte, SignatureLength))
+	require.NotNil(t, err)
+}
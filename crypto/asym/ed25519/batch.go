@@ -0,0 +1,68 @@
+package ed25519
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"filippo.io/edwards25519"
+)
+
+// BatchVerify checks the signatures sigs[i] of digests[i] by pubs[i] at once.
+// The verification equations are combined with random 128 bit weights and
+// checked with a single multi-scalar multiplication. If it fails, at least
+// one signature is invalid and the signatures need to be checked one by one
+// to find it. It follows the same rules of ZIP 215 as PublicKey.Verify, so
+// a batch passes exactly if every signature in it does.
+func BatchVerify(pubs []*PublicKey, digests [][]byte, sigs [][]byte) (bool, error) {
+	if len(pubs) == 0 || len(pubs) != len(digests) || len(pubs) != len(sigs) {
+		return false, fmt.Errorf("public keys, digests and signatures don't match")
+	}
+
+	var (
+		scalars = make([]*edwards25519.Scalar, 0, 2*len(pubs)+1)
+		points  = make([]*edwards25519.Point, 0, 2*len(pubs)+1)
+		sum     = edwards25519.NewScalar()
+		buf     = make([]byte, 32)
+	)
+	for i, pub := range pubs {
+		sig, err := rawSignature(pub.K, sigs[i])
+		if err != nil {
+			return false, err
+		}
+
+		a, err := new(edwards25519.Point).SetBytes(pub.K)
+		if err != nil {
+			return false, fmt.Errorf("invalid ed25519 public key: %w", err)
+		}
+		r, err := new(edwards25519.Point).SetBytes(sig[:32])
+		if err != nil {
+			return false, fmt.Errorf("invalid signature")
+		}
+		s, err := edwards25519.NewScalar().SetCanonicalBytes(sig[32:])
+		if err != nil {
+			return false, fmt.Errorf("invalid signature")
+		}
+		k := challenge(sig[:32], pub.K, digests[i])
+
+		if _, err := rand.Read(buf[:16]); err != nil {
+			return false, err
+		}
+		z, err := edwards25519.NewScalar().SetCanonicalBytes(buf)
+		if err != nil {
+			return false, err
+		}
+
+		sum.MultiplyAdd(z, s, sum)
+		scalars = append(scalars, z, edwards25519.NewScalar().Multiply(z, k))
+		points = append(points, r, a)
+	}
+
+	// [8]((-sum z_i*s_i)B + sum z_i*R_i + sum (z_i*k_i)A_i) is the identity
+	scalars = append(scalars, sum.Negate(sum))
+	points = append(points, edwards25519.NewGeneratorPoint())
+	check := new(edwards25519.Point).VarTimeMultiScalarMult(scalars, points)
+	if check.MultByCofactor(check).Equal(edwards25519.NewIdentityPoint()) != 1 {
+		return false, fmt.Errorf("invalid signature in batch")
+	}
+	return true, nil
+}
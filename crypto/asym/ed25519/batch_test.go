@@ -0,0 +1,110 @@
+package ed25519
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchVerify(t *testing.T) {
+	var (
+		pubs    = make([]*PublicKey, 20)
+		digests = make([][]byte, 20)
+		sigs    = make([][]byte, 20)
+	)
+	for i := range pubs {
+		priv, err := New()
+		require.Nil(t, err)
+		d := sha256.Sum256([]byte{byte(i)})
+		pubs[i] = priv.PublicKey().(*PublicKey)
+		digests[i] = d[:]
+		sigs[i], err = priv.Sign(digests[i])
+		require.Nil(t, err)
+	}
+	// Raw signatures and the same signature twice
+	sigs[1] = sigs[1][32:]
+	pubs[2], digests[2], sigs[2] = pubs[3], digests[3], sigs[3]
+
+	ok, err := BatchVerify(pubs, digests, sigs)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	// Test vector 2 from RFC 8032 section 7.1
+	pub, err := hex.DecodeString("3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c")
+	require.Nil(t, err)
+	sig, err := hex.DecodeString("92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da" +
+		"085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00")
+	require.Nil(t, err)
+	ok, err = BatchVerify(append(pubs, &PublicKey{K: pub}), append(digests, []byte{0x72}), append(sigs, sig))
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	digests[0], digests[19] = digests[19], digests[0]
+	ok, err = BatchVerify(pubs, digests, sigs)
+	require.NotNil(t, err)
+	require.False(t, ok)
+	digests[0], digests[19] = digests[19], digests[0]
+
+	// A non canonical s is rejected
+	bad := append([]byte{}, sigs[5]...)
+	bad[len(bad)-1] |= 0xf0
+	sigs[5] = bad
+	ok, err = BatchVerify(pubs, digests, sigs)
+	require.NotNil(t, err)
+	require.False(t, ok)
+
+	_, err = BatchVerify(pubs, digests[1:], sigs)
+	require.NotNil(t, err)
+}
+
+// A signature whose R carries a small order component fails the cofactorless
+// equation, Verify and BatchVerify must still agree on it.
+func TestBatchVerifySmallOrderR(t *testing.T) {
+	priv, err := New()
+	require.Nil(t, err)
+	key := priv.(*PrivateKey)
+	pub := key.PublicKey().(*PublicKey)
+	digest := sha256.Sum256([]byte("small order"))
+
+	// T is a point of order 8
+	tb, err := hex.DecodeString("c7176a703d4dd84fba3c0b760d10670f2a2053fa2c39ccc64ec7fd7792ac037a")
+	require.Nil(t, err)
+	torsion, err := new(edwards25519.Point).SetBytes(tb)
+	require.Nil(t, err)
+	require.Equal(t, 1, new(edwards25519.Point).MultByCofactor(torsion).Equal(edwards25519.NewIdentityPoint()))
+
+	// R = [r]B + T, s = r + k*a
+	h := sha512.Sum512(key.K.Seed())
+	a, err := edwards25519.NewScalar().SetBytesWithClamping(h[:32])
+	require.Nil(t, err)
+	r, err := edwards25519.NewScalar().SetUniformBytes(h[:])
+	require.Nil(t, err)
+	rb := new(edwards25519.Point).ScalarBaseMult(r)
+	rb.Add(rb, torsion)
+	k := challenge(rb.Bytes(), pub.K, digest[:])
+	s := edwards25519.NewScalar().MultiplyAdd(k, a, r)
+	sig := append(rb.Bytes(), s.Bytes()...)
+	require.False(t, ed25519.Verify(pub.K, digest[:], sig))
+
+	ok, err := pub.Verify(digest[:], sig)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	honest, err := key.Sign(digest[:])
+	require.Nil(t, err)
+	ok, err = BatchVerify([]*PublicKey{pub, pub}, [][]byte{digest[:], digest[:]}, [][]byte{honest, sig})
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	// Both reject the signature of another digest
+	other := sha256.Sum256([]byte("other"))
+	ok, _ = pub.Verify(other[:], sig)
+	require.False(t, ok)
+	ok, _ = BatchVerify([]*PublicKey{pub, pub}, [][]byte{digest[:], other[:]}, [][]byte{honest, sig})
+	require.False(t, ok)
+}
@@ -0,0 +1,224 @@
+package ed25519
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+
+	"filippo.io/edwards25519"
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/types"
+	"golang.org/x/crypto/sha3"
+)
+
+var _ crypto.PrivateKey = (*PrivateKey)(nil)
+var _ crypto.PublicKey = (*PublicKey)(nil)
+
+const (
+	// SignatureLength is the length of a signature produced by PrivateKey.Sign,
+	// the public key followed by the raw ed25519 signature.
+	SignatureLength = ed25519.PublicKeySize + ed25519.SignatureSize
+)
+
+// PrivateKey Ed25519 private key.
+type PrivateKey struct {
+	K ed25519.PrivateKey
+}
+
+// PublicKey Ed25519 public key.
+type PublicKey struct {
+	K ed25519.PublicKey
+}
+
+// New generates a ed25519 private key
+func New() (crypto.PrivateKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrivateKey{K: priv}, nil
+}
+
+func NewWithCryptoKey(priv ed25519.PrivateKey) (crypto.PrivateKey, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid ed25519 private key length %d", len(priv))
+	}
+
+	return &PrivateKey{K: priv}, nil
+}
+
+func NewPublicKey(pub ed25519.PublicKey) (*PublicKey, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key length %d", len(pub))
+	}
+
+	return &PublicKey{K: pub}, nil
+}
+
+// Bytes returns the 64 bytes private key followed by public key, which is the
+// same raw format libp2p uses for ed25519 identities.
+func (priv *PrivateKey) Bytes() ([]byte, error) {
+	if priv.K == nil {
+		return nil, fmt.Errorf("Ed25519PrivateKey.K is nil")
+	}
+
+	r := make([]byte, len(priv.K))
+	copy(r, priv.K)
+	return r, nil
+}
+
+func (priv *PrivateKey) PublicKey() crypto.PublicKey {
+	return &PublicKey{K: priv.K.Public().(ed25519.PublicKey)}
+}
+
+// Sign signs digest and prefixes the signature with the public key, since
+// ed25519 public keys can't be recovered from signatures.
+func (priv *PrivateKey) Sign(digest []byte) ([]byte, error) {
+	if priv.K == nil {
+		return nil, fmt.Errorf("Ed25519PrivateKey.K is nil")
+	}
+
+	sig := ed25519.Sign(priv.K, digest)
+	ret := make([]byte, 0, SignatureLength)
+	ret = append(ret, priv.K.Public().(ed25519.PublicKey)...)
+	return append(ret, sig...), nil
+}
+
+func (priv *PrivateKey) Type() crypto.KeyType {
+	return crypto.Ed25519
+}
+
+// UnmarshalPrivateKey parses a 32 bytes seed or a 64 bytes private key.
+func UnmarshalPrivateKey(data []byte) (*PrivateKey, error) {
+	switch len(data) {
+	case ed25519.SeedSize:
+		return &PrivateKey{K: ed25519.NewKeyFromSeed(data)}, nil
+	case ed25519.PrivateKeySize:
+		priv := ed25519.NewKeyFromSeed(data[:ed25519.SeedSize])
+		if !bytes.Equal(priv[ed25519.SeedSize:], data[ed25519.SeedSize:]) {
+			return nil, fmt.Errorf("ed25519 private key does not match its public key")
+		}
+		return &PrivateKey{K: priv}, nil
+	default:
+		return nil, fmt.Errorf("invalid ed25519 private key length %d", len(data))
+	}
+}
+
+func UnmarshalPublicKey(data []byte) (*PublicKey, error) {
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key length %d", len(data))
+	}
+
+	pub := make([]byte, ed25519.PublicKeySize)
+	copy(pub, data)
+	return &PublicKey{K: pub}, nil
+}
+
+// SigToPub returns the public key embedded in a signature produced by Sign.
+func SigToPub(sig []byte) (*PublicKey, error) {
+	if len(sig) != SignatureLength {
+		return nil, fmt.Errorf("invalid ed25519 signature length %d", len(sig))
+	}
+
+	return UnmarshalPublicKey(sig[:ed25519.PublicKeySize])
+}
+
+func (pub *PublicKey) Bytes() ([]byte, error) {
+	if pub.K == nil {
+		return nil, fmt.Errorf("Ed25519PublicKey.K is nil")
+	}
+
+	r := make([]byte, len(pub.K))
+	copy(r, pub.K)
+	return r, nil
+}
+
+// Address returns the last 20 bytes of the keccak256 hash of the public key.
+func (pub *PublicKey) Address() (*types.Address, error) {
+	if pub.K == nil {
+		return nil, fmt.Errorf("Ed25519PublicKey.K is nil")
+	}
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(pub.K)
+	ret := hash.Sum(nil)
+
+	return types.NewAddress(ret[12:]), nil
+}
+
+// Verify checks either a signature produced by Sign or a raw 64 bytes
+// ed25519 signature, with the rules of ZIP 215 like BatchVerify.
+func (pub *PublicKey) Verify(digest []byte, sig []byte) (bool, error) {
+	if sig == nil {
+		return false, fmt.Errorf("nil signature")
+	}
+
+	sig, err := rawSignature(pub.K, sig)
+	if err != nil {
+		return false, err
+	}
+
+	if !verify(pub.K, digest, sig) {
+		return false, fmt.Errorf("invalid signature")
+	}
+
+	return true, nil
+}
+
+// verify checks a raw signature with the rules of ZIP 215: R and the public
+// key may have any encoding of a point, s must be canonical and the
+// cofactored equation [8][s]B = [8]R + [8][k]A must hold. Unlike the
+// cofactorless ed25519.Verify, the single and the batch verification then
+// accept exactly the same signatures.
+func verify(pub, digest, sig []byte) bool {
+	a, err := new(edwards25519.Point).SetBytes(pub)
+	if err != nil {
+		return false
+	}
+	r, err := new(edwards25519.Point).SetBytes(sig[:32])
+	if err != nil {
+		return false
+	}
+	s, err := edwards25519.NewScalar().SetCanonicalBytes(sig[32:])
+	if err != nil {
+		return false
+	}
+
+	// [8]([s]B - [k]A - R) is the identity
+	k := challenge(sig[:32], pub, digest)
+	check := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(k.Negate(k), a, s)
+	check.Subtract(check, r)
+	return check.MultByCofactor(check).Equal(edwards25519.NewIdentityPoint()) == 1
+}
+
+// challenge computes k = SHA-512(R || A || M) modulo the group order
+func challenge(r, pub, digest []byte) *edwards25519.Scalar {
+	hash := sha512.New()
+	hash.Write(r)
+	hash.Write(pub)
+	hash.Write(digest)
+	k, _ := edwards25519.NewScalar().SetUniformBytes(hash.Sum(nil))
+	return k
+}
+
+func (pub *PublicKey) Type() crypto.KeyType {
+	return crypto.Ed25519
+}
+
+// rawSignature strips the public key off a signature produced by Sign
+func rawSignature(pub, sig []byte) ([]byte, error) {
+	switch len(sig) {
+	case SignatureLength:
+		if !bytes.Equal(sig[:ed25519.PublicKeySize], pub) {
+			return nil, fmt.Errorf("signature is signed by another public key")
+		}
+		return sig[ed25519.PublicKeySize:], nil
+	case ed25519.SignatureSize:
+		return sig, nil
+	default:
+		return nil, fmt.Errorf("invalid ed25519 signature length %d", len(sig))
+	}
+}
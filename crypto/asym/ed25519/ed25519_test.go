@@ -0,0 +1,88 @@
+package ed25519
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	digest := sha256.Sum256([]byte("hyperchain"))
+	priv, err := New()
+	require.Nil(t, err)
+
+	sig, err := priv.Sign(digest[:])
+	require.Nil(t, err)
+	require.Equal(t, SignatureLength, len(sig))
+
+	b, err := priv.PublicKey().Verify(digest[:], sig)
+	require.Nil(t, err)
+	require.True(t, b)
+
+	// Raw signatures without public key
+	b, err = priv.PublicKey().Verify(digest[:], sig[32:])
+	require.Nil(t, err)
+	require.True(t, b)
+
+	wrongDigest := sha256.Sum256([]byte("hypercha1n"))
+	b, err = priv.PublicKey().Verify(wrongDigest[:], sig)
+	require.NotNil(t, err)
+	require.False(t, b)
+
+	other, err := New()
+	require.Nil(t, err)
+	_, err = other.PublicKey().Verify(digest[:], sig)
+	require.NotNil(t, err)
+}
+
+func TestMarshal(t *testing.T) {
+	priv, err := New()
+	require.Nil(t, err)
+
+	data, err := priv.Bytes()
+	require.Nil(t, err)
+	restored, err := UnmarshalPrivateKey(data)
+	require.Nil(t, err)
+	require.Equal(t, priv, restored)
+
+	fromSeed, err := UnmarshalPrivateKey(data[:32])
+	require.Nil(t, err)
+	require.Equal(t, priv, fromSeed)
+
+	data[63] ^= 0xff
+	_, err = UnmarshalPrivateKey(data)
+	require.NotNil(t, err)
+
+	pubData, err := priv.PublicKey().Bytes()
+	require.Nil(t, err)
+	pub, err := UnmarshalPublicKey(pubData)
+	require.Nil(t, err)
+
+	addr1, err := priv.PublicKey().Address()
+	require.Nil(t, err)
+	addr2, err := pub.Address()
+	require.Nil(t, err)
+	require.Equal(t, addr1, addr2)
+}
+
+func TestLibp2pCompatible(t *testing.T) {
+	priv, err := New()
+	require.Nil(t, err)
+	data, err := priv.Bytes()
+	require.Nil(t, err)
+
+	p2pKey, err := crypto.UnmarshalEd25519PrivateKey(data)
+	require.Nil(t, err)
+	raw, err := p2pKey.Raw()
+	require.Nil(t, err)
+	require.Equal(t, data, raw)
+
+	digest := sha256.Sum256([]byte("hyperchain"))
+	sig, err := p2pKey.Sign(digest[:])
+	require.Nil(t, err)
+	b, err := priv.PublicKey().Verify(digest[:], sig)
+	require.Nil(t, err)
+	require.True(t, b)
+}
@@ -0,0 +1,570 @@
+package asym
+
+import (
+	"bytes"
+	ecdsa2 "crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	rsa2 "crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/ecdsa"
+	ed25519key "github.com/meshplus/bitxhub-kit/crypto/asym/ed25519"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/rsa"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/sm2"
+)
+
+// KeyFormat is an encoding of keys which other tools understand
+type KeyFormat int
+
+const (
+	// FormatPEM is the PEM encoding of FormatDER
+	FormatPEM KeyFormat = iota
+	// FormatDER is PKCS#8 for private keys and SubjectPublicKeyInfo for public keys
+	FormatDER
+	// FormatJWK is a JSON Web Key, see RFC 7517
+	FormatJWK
+)
+
+const (
+	pemPrivateKey       = "PRIVATE KEY"
+	pemPublicKey        = "PUBLIC KEY"
+	ecPrivateKeyVersion = 1
+)
+
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidNamedCurveS256 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+	oidNamedCurveSM2  = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
+)
+
+// pkcs8 and ecPrivateKey mirror the structures of crypto/x509, which only
+// handles the NIST curves.
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// jwk holds the members of EC, RSA and OKP JSON Web Keys
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	Dp  string `json:"dp,omitempty"`
+	Dq  string `json:"dq,omitempty"`
+	Qi  string `json:"qi,omitempty"`
+}
+
+// MarshalPrivateKey encodes a ECDSA, SM2, Ed25519 or RSA private key.
+func MarshalPrivateKey(priv crypto.PrivateKey, format KeyFormat) ([]byte, error) {
+	switch format {
+	case FormatPEM:
+		der, err := marshalPKCS8(priv)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: pemPrivateKey, Bytes: der}), nil
+	case FormatDER:
+		return marshalPKCS8(priv)
+	case FormatJWK:
+		return marshalPrivateJWK(priv)
+	default:
+		return nil, fmt.Errorf("unknown key format %d", format)
+	}
+}
+
+// MarshalPublicKey encodes a ECDSA, SM2, Ed25519 or RSA public key.
+func MarshalPublicKey(pub crypto.PublicKey, format KeyFormat) ([]byte, error) {
+	switch format {
+	case FormatPEM:
+		der, err := marshalSPKI(pub)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: pemPublicKey, Bytes: der}), nil
+	case FormatDER:
+		return marshalSPKI(pub)
+	case FormatJWK:
+		return marshalPublicJWK(pub)
+	default:
+		return nil, fmt.Errorf("unknown key format %d", format)
+	}
+}
+
+// ParsePrivateKey decodes a private key in any KeyFormat. PEM and DER also
+// accept SEC1 EC and PKCS#1 RSA private keys.
+func ParsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	format, data := detectFormat(data)
+	switch format {
+	case FormatJWK:
+		return parsePrivateJWK(data)
+	case FormatPEM:
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("invalid pem private key")
+		}
+		return parsePrivateDER(block.Bytes)
+	default:
+		return parsePrivateDER(data)
+	}
+}
+
+// ParsePublicKey decodes a public key in any KeyFormat. PEM and DER also
+// accept PKCS#1 RSA public keys.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	format, data := detectFormat(data)
+	switch format {
+	case FormatJWK:
+		return parsePublicJWK(data)
+	case FormatPEM:
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("invalid pem public key")
+		}
+		return parsePublicDER(block.Bytes)
+	default:
+		return parsePublicDER(data)
+	}
+}
+
+// detectFormat tells the KeyFormat of encoded key data. The text formats are
+// returned without surrounding whitespace, DER is binary and may well end
+// with whitespace bytes.
+func detectFormat(data []byte) (KeyFormat, []byte) {
+	text := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(text, []byte("{")):
+		return FormatJWK, text
+	case bytes.HasPrefix(text, []byte("-----BEGIN")):
+		return FormatPEM, text
+	default:
+		return FormatDER, data
+	}
+}
+
+func marshalPKCS8(priv crypto.PrivateKey) ([]byte, error) {
+	switch key := priv.(type) {
+	case *ecdsa.PrivateKey:
+		if key.Type() == crypto.Secp256k1 {
+			return marshalECPKCS8(key.K, oidNamedCurveS256)
+		}
+		return x509.MarshalPKCS8PrivateKey(key.K)
+	case *sm2.PrivateKey:
+		return marshalECPKCS8(key.K, oidNamedCurveSM2)
+	case *ed25519key.PrivateKey:
+		return x509.MarshalPKCS8PrivateKey(key.K)
+	case *rsa.PrivateKey:
+		return x509.MarshalPKCS8PrivateKey(key.K)
+	default:
+		return nil, fmt.Errorf("key type %d can't be encoded", priv.Type())
+	}
+}
+
+func marshalSPKI(pub crypto.PublicKey) ([]byte, error) {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if key.Type() == crypto.Secp256k1 {
+			return marshalECSPKI(key.K, oidNamedCurveS256)
+		}
+		return x509.MarshalPKIXPublicKey(key.K)
+	case *sm2.PublicKey:
+		return marshalECSPKI(key.K, oidNamedCurveSM2)
+	case *ed25519key.PublicKey:
+		return x509.MarshalPKIXPublicKey(key.K)
+	case *rsa.PublicKey:
+		return x509.MarshalPKIXPublicKey(key.K)
+	default:
+		return nil, fmt.Errorf("key type %d can't be encoded", pub.Type())
+	}
+}
+
+func ecAlgorithm(curveOID asn1.ObjectIdentifier) (pkix.AlgorithmIdentifier, error) {
+	params, err := asn1.Marshal(curveOID)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{
+		Algorithm:  oidPublicKeyECDSA,
+		Parameters: asn1.RawValue{FullBytes: params},
+	}, nil
+}
+
+func marshalECPKCS8(key *ecdsa2.PrivateKey, curveOID asn1.ObjectIdentifier) ([]byte, error) {
+	algo, err := ecAlgorithm(curveOID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The curve is named by the algorithm parameters and left out here
+	point := elliptic.Marshal(key.Curve, key.X, key.Y)
+	ecKey, err := asn1.Marshal(ecPrivateKey{
+		Version:    ecPrivateKeyVersion,
+		PrivateKey: ecdsa.PaddedBigBytes(key.D, (key.Curve.Params().N.BitLen()+7)/8),
+		PublicKey:  asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8{Algo: algo, PrivateKey: ecKey})
+}
+
+func marshalECSPKI(key *ecdsa2.PublicKey, curveOID asn1.ObjectIdentifier) ([]byte, error) {
+	algo, err := ecAlgorithm(curveOID)
+	if err != nil {
+		return nil, err
+	}
+	point := elliptic.Marshal(key.Curve, key.X, key.Y)
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: algo,
+		PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+}
+
+func parsePrivateDER(der []byte) (crypto.PrivateKey, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return PrivateKeyFromStdKey(key)
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return PrivateKeyFromStdKey(key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return PrivateKeyFromStdKey(key)
+	}
+
+	// secp256k1 and SM2 keys, in PKCS#8 or SEC1
+	var (
+		p8       pkcs8
+		curveOID asn1.ObjectIdentifier
+	)
+	ecDER := der
+	if rest, err := asn1.Unmarshal(der, &p8); err == nil && len(rest) == 0 && p8.Algo.Algorithm.Equal(oidPublicKeyECDSA) {
+		if _, err := asn1.Unmarshal(p8.Algo.Parameters.FullBytes, &curveOID); err != nil {
+			return nil, fmt.Errorf("invalid ec private key curve: %w", err)
+		}
+		ecDER = p8.PrivateKey
+	}
+
+	var ecKey ecPrivateKey
+	if rest, err := asn1.Unmarshal(ecDER, &ecKey); err != nil || len(rest) != 0 || ecKey.Version != ecPrivateKeyVersion {
+		return nil, fmt.Errorf("unknown private key format")
+	}
+	if len(curveOID) == 0 {
+		curveOID = ecKey.NamedCurveOID
+	}
+
+	switch {
+	case curveOID.Equal(oidNamedCurveS256):
+		key, err := ecdsa.ToECDSA(ecKey.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsa.NewWithCryptoKey(key)
+	case curveOID.Equal(oidNamedCurveSM2):
+		return sm2.UnmarshalPrivateKey(ecKey.PrivateKey)
+	default:
+		return nil, fmt.Errorf("unsupported ec private key curve %s", curveOID)
+	}
+}
+
+func parsePublicDER(der []byte) (crypto.PublicKey, error) {
+	if key, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return PubKeyFromStdKey(key)
+	}
+	if key, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return PubKeyFromStdKey(key)
+	}
+
+	// secp256k1 and SM2 keys
+	var (
+		spki     subjectPublicKeyInfo
+		curveOID asn1.ObjectIdentifier
+	)
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil || len(rest) != 0 || !spki.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		return nil, fmt.Errorf("unknown public key format")
+	}
+	if _, err := asn1.Unmarshal(spki.Algorithm.Parameters.FullBytes, &curveOID); err != nil {
+		return nil, fmt.Errorf("invalid ec public key curve: %w", err)
+	}
+
+	switch {
+	case curveOID.Equal(oidNamedCurveS256):
+		return ecdsa.UnmarshalPublicKey(spki.PublicKey.RightAlign(), crypto.Secp256k1)
+	case curveOID.Equal(oidNamedCurveSM2):
+		return sm2.UnmarshalPublicKey(spki.PublicKey.RightAlign())
+	default:
+		return nil, fmt.Errorf("unsupported ec public key curve %s", curveOID)
+	}
+}
+
+// jwkCurves maps key types to the JOSE curve names of RFC 7518 and RFC 8812
+var jwkCurves = map[crypto.KeyType]string{
+	crypto.ECDSA_P256: "P-256",
+	crypto.ECDSA_P384: "P-384",
+	crypto.ECDSA_P521: "P-521",
+	crypto.Secp256k1:  "secp256k1",
+}
+
+func encodeJWK(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func encodeJWKInt(n *big.Int) string {
+	return encodeJWK(n.Bytes())
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty jwk member")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func publicJWK(pub crypto.PublicKey) (*jwk, error) {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		crv, ok := jwkCurves[key.Type()]
+		if !ok {
+			return nil, fmt.Errorf("key type %d can't be encoded", pub.Type())
+		}
+		size := (key.K.Curve.Params().BitSize + 7) / 8
+		return &jwk{
+			Kty: "EC",
+			Crv: crv,
+			X:   encodeJWK(ecdsa.PaddedBigBytes(key.K.X, size)),
+			Y:   encodeJWK(ecdsa.PaddedBigBytes(key.K.Y, size)),
+		}, nil
+	case *ed25519key.PublicKey:
+		return &jwk{Kty: "OKP", Crv: "Ed25519", X: encodeJWK(key.K)}, nil
+	case *rsa.PublicKey:
+		return &jwk{Kty: "RSA", N: encodeJWKInt(key.K.N), E: encodeJWKInt(big.NewInt(int64(key.K.E)))}, nil
+	default:
+		return nil, fmt.Errorf("key type %d can't be encoded", pub.Type())
+	}
+}
+
+func marshalPublicJWK(pub crypto.PublicKey) ([]byte, error) {
+	key, err := publicJWK(pub)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(key)
+}
+
+func marshalPrivateJWK(priv crypto.PrivateKey) ([]byte, error) {
+	key, err := publicJWK(priv.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := priv.(type) {
+	case *ecdsa.PrivateKey:
+		key.D = encodeJWK(ecdsa.PaddedBigBytes(k.K.D, (k.K.Curve.Params().N.BitLen()+7)/8))
+	case *ed25519key.PrivateKey:
+		key.D = encodeJWK(k.K.Seed())
+	case *rsa.PrivateKey:
+		if len(k.K.Primes) != 2 {
+			return nil, fmt.Errorf("multi-prime rsa keys can't be encoded")
+		}
+		k.K.Precompute()
+		key.D = encodeJWKInt(k.K.D)
+		key.P = encodeJWKInt(k.K.Primes[0])
+		key.Q = encodeJWKInt(k.K.Primes[1])
+		key.Dp = encodeJWKInt(k.K.Precomputed.Dp)
+		key.Dq = encodeJWKInt(k.K.Precomputed.Dq)
+		key.Qi = encodeJWKInt(k.K.Precomputed.Qinv)
+	default:
+		return nil, fmt.Errorf("key type %d can't be encoded", priv.Type())
+	}
+	return json.Marshal(key)
+}
+
+func parseJWK(data []byte) (*jwk, error) {
+	key := &jwk{}
+	if err := json.Unmarshal(data, key); err != nil {
+		return nil, fmt.Errorf("invalid jwk: %w", err)
+	}
+	return key, nil
+}
+
+func jwkCurve(crv string) (elliptic.Curve, error) {
+	switch crv {
+	case "P-256":
+		return elliptic.P256(), nil
+	case "P-384":
+		return elliptic.P384(), nil
+	case "P-521":
+		return elliptic.P521(), nil
+	case "secp256k1":
+		return ecdsa.S256(), nil
+	default:
+		return nil, fmt.Errorf("unsupported jwk curve %q", crv)
+	}
+}
+
+func parsePublicJWK(data []byte) (crypto.PublicKey, error) {
+	key, err := parseJWK(data)
+	if err != nil {
+		return nil, err
+	}
+	return key.publicKey()
+}
+
+func (key *jwk) publicKey() (crypto.PublicKey, error) {
+	switch key.Kty {
+	case "EC":
+		curve, err := jwkCurve(key.Crv)
+		if err != nil {
+			return nil, err
+		}
+		x, err := decodeJWKInt(key.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(key.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("invalid jwk ec point")
+		}
+		return ecdsa.NewPublicKey(ecdsa2.PublicKey{Curve: curve, X: x, Y: y})
+	case "OKP":
+		if key.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported jwk curve %q", key.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(key.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519key.UnmarshalPublicKey(x)
+	case "RSA":
+		n, err := decodeJWKInt(key.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(key.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid jwk rsa exponent")
+		}
+		return rsa.NewPublicKey(&rsa2.PublicKey{N: n, E: int(e.Int64())})
+	default:
+		return nil, fmt.Errorf("unsupported jwk key type %q", key.Kty)
+	}
+}
+
+func parsePrivateJWK(data []byte) (crypto.PrivateKey, error) {
+	key, err := parseJWK(data)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := key.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	if key.D == "" {
+		return nil, fmt.Errorf("jwk is not a private key")
+	}
+
+	var priv crypto.PrivateKey
+	switch p := pub.(type) {
+	case *ecdsa.PublicKey:
+		d, err := decodeJWKInt(key.D)
+		if err != nil {
+			return nil, err
+		}
+		if d.Sign() == 0 || d.Cmp(p.K.Curve.Params().N) >= 0 {
+			return nil, fmt.Errorf("invalid jwk ec private key")
+		}
+		// Derive the public point from d, so that the check below compares
+		// it with x and y
+		std := &ecdsa2.PrivateKey{PublicKey: ecdsa2.PublicKey{Curve: p.K.Curve}, D: d}
+		std.X, std.Y = p.K.Curve.ScalarBaseMult(d.Bytes())
+		if priv, err = ecdsa.NewWithCryptoKey(std); err != nil {
+			return nil, err
+		}
+	case *ed25519key.PublicKey:
+		seed, err := base64.RawURLEncoding.DecodeString(key.D)
+		if err != nil {
+			return nil, err
+		}
+		if len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid jwk ed25519 private key")
+		}
+		if priv, err = ed25519key.UnmarshalPrivateKey(seed); err != nil {
+			return nil, err
+		}
+	case *rsa.PublicKey:
+		std := &rsa2.PrivateKey{PublicKey: *p.K}
+		if std.D, err = decodeJWKInt(key.D); err != nil {
+			return nil, err
+		}
+		p1, err := decodeJWKInt(key.P)
+		if err != nil {
+			return nil, err
+		}
+		q, err := decodeJWKInt(key.Q)
+		if err != nil {
+			return nil, err
+		}
+		std.Primes = []*big.Int{p1, q}
+		if err := std.Validate(); err != nil {
+			return nil, err
+		}
+		std.Precompute()
+		if priv, err = rsa.NewWithCryptoKey(std); err != nil {
+			return nil, err
+		}
+	}
+
+	// The private key must match the public members
+	if !publicKeyEqual(priv.PublicKey(), pub) {
+		return nil, fmt.Errorf("jwk private key does not match its public key")
+	}
+	return priv, nil
+}
+
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	ab, err := a.Bytes()
+	if err != nil {
+		return false
+	}
+	bb, err := b.Bytes()
+	if err != nil {
+		return false
+	}
+	return a.Type() == b.Type() && bytes.Equal(ab, bb)
+}
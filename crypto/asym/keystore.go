@@ -0,0 +1,358 @@
+package asym
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/sym"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// KeyStoreVersion is the version of the Web3 Secret Storage format
+	KeyStoreVersion = 3
+
+	KDFScrypt   = "scrypt"
+	KDFArgon2id = "argon2id"
+
+	// StandardScryptN and StandardScryptP are the scrypt parameters used by default
+	StandardScryptN = 1 << 18
+	StandardScryptP = 1
+
+	// LightScryptN and LightScryptP are cheaper scrypt parameters for
+	// constrained environments and tests
+	LightScryptN = 1 << 12
+	LightScryptP = 6
+
+	DefaultArgon2Time    = 1
+	DefaultArgon2Memory  = 64 * 1024 // KiB
+	DefaultArgon2Threads = 4
+
+	keyStoreCipher = "aes-128-ctr"
+	scryptR        = 8
+	kdfKeyLen      = 32
+	kdfSaltLen     = 32
+)
+
+type keyStoreConfig struct {
+	kdf           string
+	scryptN       int
+	scryptP       int
+	argon2Time    uint32
+	argon2Memory  uint32
+	argon2Threads uint8
+}
+
+type KeyStoreOption func(*keyStoreConfig)
+
+// WithScrypt derives the encryption key with scrypt using the given cost parameters.
+func WithScrypt(n, p int) KeyStoreOption {
+	return func(c *keyStoreConfig) {
+		c.kdf = KDFScrypt
+		c.scryptN = n
+		c.scryptP = p
+	}
+}
+
+// WithLightScrypt derives the encryption key with the light scrypt parameters.
+func WithLightScrypt() KeyStoreOption {
+	return WithScrypt(LightScryptN, LightScryptP)
+}
+
+// WithArgon2id derives the encryption key with argon2id, memory is in KiB.
+func WithArgon2id(time, memory uint32, threads uint8) KeyStoreOption {
+	return func(c *keyStoreConfig) {
+		c.kdf = KDFArgon2id
+		c.argon2Time = time
+		c.argon2Memory = memory
+		c.argon2Threads = threads
+	}
+}
+
+func generateKeyStoreConfig(opts ...KeyStoreOption) (*keyStoreConfig, error) {
+	conf := &keyStoreConfig{
+		kdf:     KDFScrypt,
+		scryptN: StandardScryptN,
+		scryptP: StandardScryptP,
+	}
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	switch conf.kdf {
+	case KDFScrypt:
+		if conf.scryptN <= 1 || conf.scryptN&(conf.scryptN-1) != 0 || conf.scryptP <= 0 {
+			return nil, fmt.Errorf("invalid scrypt parameters n=%d p=%d", conf.scryptN, conf.scryptP)
+		}
+	case KDFArgon2id:
+		if conf.argon2Time == 0 || conf.argon2Memory == 0 || conf.argon2Threads == 0 {
+			return nil, fmt.Errorf("invalid argon2id parameters")
+		}
+	}
+
+	return conf, nil
+}
+
+// GenKeyStore encrypts the private key with a key derived from the password,
+// the result is a version 3 keystore.
+func GenKeyStore(priv crypto.PrivateKey, password string, opts ...KeyStoreOption) (*crypto.KeyStore, error) {
+	conf, err := generateKeyStoreConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	privBytes, err := priv.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, kdfSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	params := crypto.KDFParams{
+		DKLen: kdfKeyLen,
+		Salt:  hex.EncodeToString(salt),
+	}
+	switch conf.kdf {
+	case KDFScrypt:
+		params.N = conf.scryptN
+		params.R = scryptR
+		params.P = conf.scryptP
+	case KDFArgon2id:
+		params.Time = conf.argon2Time
+		params.Memory = conf.argon2Memory
+		params.Threads = conf.argon2Threads
+	}
+	derivedKey, err := deriveKey(password, conf.kdf, &params)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	cipherText, err := aesCTR(derivedKey[:16], iv, privBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	var address string
+	if addr, err := priv.PublicKey().Address(); err == nil {
+		address = strings.ToLower(strings.TrimPrefix(addr.String(), "0x"))
+	}
+
+	return &crypto.KeyStore{
+		Type:    priv.Type(),
+		Version: KeyStoreVersion,
+		ID:      id,
+		Address: address,
+		Crypto: &crypto.KeyStoreCrypto{
+			Cipher:       keyStoreCipher,
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: crypto.CipherParams{IV: hex.EncodeToString(iv)},
+			KDF:          conf.kdf,
+			KDFParams:    params,
+			MAC:          hex.EncodeToString(keyStoreMAC(derivedKey, cipherText)),
+		},
+	}, nil
+}
+
+// DecryptKeyStore decrypts a version 3 or a legacy keystore.
+func DecryptKeyStore(keyStore *crypto.KeyStore, password string) (crypto.PrivateKey, error) {
+	var (
+		rawBytes []byte
+		err      error
+	)
+	switch {
+	case keyStore.Version == KeyStoreVersion && keyStore.Crypto != nil:
+		rawBytes, err = decryptKeyStoreV3(keyStore.Crypto, password)
+	case keyStore.Version == 0 && keyStore.Cipher != nil:
+		rawBytes, err = decryptLegacyKeyStore(keyStore.Cipher, password)
+	default:
+		return nil, fmt.Errorf("unsupported keystore version %d", keyStore.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalPrivateKey(rawBytes, keyStore.Type)
+}
+
+// IsLegacyKeyStore reports whether the keystore uses the unversioned format,
+// which derives its key from a single unsalted hash of the password.
+func IsLegacyKeyStore(keyStore *crypto.KeyStore) bool {
+	return keyStore.Version == 0 && keyStore.Cipher != nil
+}
+
+// MigrateKeyStore rewrites a legacy keystore file in the version 3 format.
+// Files already in the version 3 format are left untouched.
+func MigrateKeyStore(keyFilePath, password string, opts ...KeyStoreOption) error {
+	data, err := ioutil.ReadFile(keyFilePath)
+	if err != nil {
+		return err
+	}
+	keyStore := &crypto.KeyStore{}
+	if err := json.Unmarshal(data, keyStore); err != nil {
+		return err
+	}
+	if !IsLegacyKeyStore(keyStore) {
+		return nil
+	}
+
+	priv, err := DecryptKeyStore(keyStore, password)
+	if err != nil {
+		return err
+	}
+
+	return StorePrivateKey(priv, keyFilePath, password, opts...)
+}
+
+// StoreKeyStore writes the keystore to a file. It goes to a temporary file in
+// the same directory first, which is renamed over the target once it is
+// synced, so that a crash or a full disk never destroys the former keystore.
+func StoreKeyStore(keyStore *crypto.KeyStore, keyFilePath string) error {
+	data, err := json.MarshalIndent(keyStore, "", " ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(keyFilePath), ".keystore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), keyFilePath)
+}
+
+func decryptKeyStoreV3(c *crypto.KeyStoreCrypto, password string) ([]byte, error) {
+	if c.Cipher != keyStoreCipher {
+		return nil, fmt.Errorf("unsupported keystore cipher %s", c.Cipher)
+	}
+	if c.KDFParams.DKLen < kdfKeyLen {
+		return nil, fmt.Errorf("derived key length %d is too short", c.KDFParams.DKLen)
+	}
+	cipherText, err := hex.DecodeString(c.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(c.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := hex.DecodeString(c.MAC)
+	if err != nil {
+		return nil, err
+	}
+
+	derivedKey, err := deriveKey(password, c.KDF, &c.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(keyStoreMAC(derivedKey, cipherText), mac) != 1 {
+		return nil, fmt.Errorf("could not decrypt key with given password")
+	}
+
+	return aesCTR(derivedKey[:16], iv, cipherText)
+}
+
+func decryptLegacyKeyStore(c *crypto.CipherKey, password string) ([]byte, error) {
+	rawBytes, err := hex.DecodeString(c.Data)
+	if err != nil {
+		return nil, err
+	}
+	if password == "" {
+		return rawBytes, nil
+	}
+
+	hash := sha256.Sum256([]byte(password))
+	aesKey, err := sym.GenerateSymKey(crypto.AES, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return aesKey.Decrypt(rawBytes)
+}
+
+func deriveKey(password, kdf string, params *crypto.KDFParams) ([]byte, error) {
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kdf {
+	case KDFScrypt:
+		return scrypt.Key([]byte(password), salt, params.N, params.R, params.P, params.DKLen)
+	case KDFArgon2id:
+		if params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+			return nil, fmt.Errorf("invalid argon2id parameters")
+		}
+		return argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(params.DKLen)), nil
+	default:
+		return nil, fmt.Errorf("unsupported kdf %s", kdf)
+	}
+}
+
+// keyStoreMAC is keccak256(derivedKey[16:32] || cipherText)
+func keyStoreMAC(derivedKey, cipherText []byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(derivedKey[16:32])
+	hash.Write(cipherText)
+	return hash.Sum(nil)
+}
+
+func aesCTR(key, iv, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid iv length %d", len(iv))
+	}
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	return out, nil
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() (string, error) {
+	u := make([]byte, 16)
+	if _, err := rand.Read(u); err != nil {
+		return "", err
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
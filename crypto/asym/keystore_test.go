@@ -0,0 +1,158 @@
+package asym
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/sym"
+	"github.com/stretchr/testify/require"
+)
+
+// Test vector from the Web3 Secret Storage Definition, with the key type added
+const web3KeyStore = `{
+	"type": 3,
+	"crypto": {
+		"cipher": "aes-128-ctr",
+		"cipherparams": {"iv": "83dbcc02d8ccb40e466191a123791e0e"},
+		"ciphertext": "d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c",
+		"kdf": "scrypt",
+		"kdfparams": {
+			"dklen": 32,
+			"n": 262144,
+			"r": 1,
+			"p": 8,
+			"salt": "ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"
+		},
+		"mac": "2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"
+	},
+	"id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+	"version": 3
+}`
+
+func TestKeyStoreWeb3Vector(t *testing.T) {
+	keyStore := &crypto.KeyStore{}
+	require.Nil(t, json.Unmarshal([]byte(web3KeyStore), keyStore))
+
+	priv, err := DecryptKeyStore(keyStore, "testpassword")
+	require.Nil(t, err)
+	raw, err := priv.Bytes()
+	require.Nil(t, err)
+	require.Equal(t, "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d", hex.EncodeToString(raw))
+
+	_, err = DecryptKeyStore(keyStore, "wrongpassword")
+	require.NotNil(t, err)
+}
+
+func TestKeyStoreArgon2id(t *testing.T) {
+	priv, err := GenerateKeyPair(crypto.Secp256k1)
+	require.Nil(t, err)
+
+	keyStore, err := GenKeyStore(priv, "key", WithArgon2id(1, 1024, 1))
+	require.Nil(t, err)
+	require.Equal(t, KeyStoreVersion, keyStore.Version)
+	require.Equal(t, KDFArgon2id, keyStore.Crypto.KDF)
+	require.Nil(t, keyStore.Cipher)
+
+	addr, err := priv.PublicKey().Address()
+	require.Nil(t, err)
+	require.Equal(t, hex.EncodeToString(addr.Bytes()), keyStore.Address)
+
+	restored, err := DecryptKeyStore(keyStore, "key")
+	require.Nil(t, err)
+	restoredAddr, err := restored.PublicKey().Address()
+	require.Nil(t, err)
+	require.Equal(t, addr.String(), restoredAddr.String())
+
+	_, err = DecryptKeyStore(keyStore, "wrong")
+	require.NotNil(t, err)
+
+	_, err = GenKeyStore(priv, "key", WithScrypt(1000, 1))
+	require.NotNil(t, err)
+}
+
+func TestMigrateKeyStore(t *testing.T) {
+	priv, err := GenerateKeyPair(crypto.ECDSA_P256)
+	require.Nil(t, err)
+	addr, err := priv.PublicKey().Address()
+	require.Nil(t, err)
+
+	// Write a keystore in the legacy format
+	privBytes, err := priv.Bytes()
+	require.Nil(t, err)
+	hash := sha256.Sum256([]byte("key"))
+	aesKey, err := sym.GenerateSymKey(crypto.AES, hash[:])
+	require.Nil(t, err)
+	encrypted, err := aesKey.Encrypt(privBytes)
+	require.Nil(t, err)
+	data, err := json.Marshal(&crypto.KeyStore{
+		Type: priv.Type(),
+		Cipher: &crypto.CipherKey{
+			Cipher: "AES-256",
+			Data:   hex.EncodeToString(encrypted),
+		},
+	})
+	require.Nil(t, err)
+
+	keyFile := filepath.Join(os.TempDir(), "legacy_priv.json")
+	defer os.Remove(keyFile)
+	require.Nil(t, ioutil.WriteFile(keyFile, data, 0600))
+
+	restored, err := RestorePrivateKey(keyFile, "key")
+	require.Nil(t, err)
+	restoredAddr, err := restored.PublicKey().Address()
+	require.Nil(t, err)
+	require.Equal(t, addr.String(), restoredAddr.String())
+
+	require.NotNil(t, MigrateKeyStore(keyFile, "wrong", WithLightScrypt()))
+	require.Nil(t, MigrateKeyStore(keyFile, "key", WithLightScrypt()))
+
+	data, err = ioutil.ReadFile(keyFile)
+	require.Nil(t, err)
+	keyStore := &crypto.KeyStore{}
+	require.Nil(t, json.Unmarshal(data, keyStore))
+	require.False(t, IsLegacyKeyStore(keyStore))
+	require.Equal(t, KeyStoreVersion, keyStore.Version)
+
+	restored, err = RestorePrivateKey(keyFile, "key")
+	require.Nil(t, err)
+	restoredAddr, err = restored.PublicKey().Address()
+	require.Nil(t, err)
+	require.Equal(t, addr.String(), restoredAddr.String())
+
+	// Migrating again is a no-op
+	require.Nil(t, MigrateKeyStore(keyFile, "key"))
+	after, err := ioutil.ReadFile(keyFile)
+	require.Nil(t, err)
+	require.Equal(t, data, after)
+}
+
+func TestStoreKeyStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "priv.json")
+	for i := 0; i < 2; i++ {
+		priv, err := GenerateKeyPair(crypto.Secp256k1)
+		require.Nil(t, err)
+		require.Nil(t, StorePrivateKey(priv, keyFile, "key", WithLightScrypt()))
+
+		restored, err := RestorePrivateKey(keyFile, "key")
+		require.Nil(t, err)
+		require.Equal(t, priv.PublicKey(), restored.PublicKey())
+	}
+
+	// The key is replaced in one step, no temporary file is left behind
+	files, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(files))
+	require.Equal(t, os.FileMode(0600), files[0].Mode().Perm())
+
+	require.NotNil(t, StoreKeyStore(&crypto.KeyStore{}, filepath.Join(dir, "missing", "priv.json")))
+}
@@ -0,0 +1,214 @@
+package asym
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/types"
+	"golang.org/x/crypto/sha3"
+)
+
+// multiSigPrefix separates multisig addresses from addresses of public keys
+var multiSigPrefix = []byte("bitxhub-multisig")
+
+// MultiSigAccount is an m-of-n account, which is controlled by any Threshold
+// of its members. The members are identified by the addresses of their
+// public keys in ascending order.
+type MultiSigAccount struct {
+	Threshold int
+	Members   []types.Address
+}
+
+// MultiSig is the signature of a multisig account. It carries the account,
+// so that the verifier can derive its address, and the signatures of some
+// members in the SignWithType format.
+type MultiSig struct {
+	Threshold int
+	Members   [][]byte
+	Sigs      []MultiSigItem
+}
+
+// MultiSigItem is the signature of the member at Index.
+type MultiSigItem struct {
+	Index int
+	Sig   []byte
+}
+
+// NewMultiSigAccount creates the account of the given public keys, their
+// order doesn't matter.
+func NewMultiSigAccount(threshold int, pubKeys []crypto.PublicKey) (*MultiSigAccount, error) {
+	members := make([]types.Address, 0, len(pubKeys))
+	for _, pub := range pubKeys {
+		addr, err := pub.Address()
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, *addr)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return bytes.Compare(members[i].Bytes(), members[j].Bytes()) < 0
+	})
+
+	account := &MultiSigAccount{Threshold: threshold, Members: members}
+	if err := account.check(); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (a *MultiSigAccount) check() error {
+	if a.Threshold < 1 || a.Threshold > len(a.Members) {
+		return fmt.Errorf("invalid multisig threshold %d of %d members", a.Threshold, len(a.Members))
+	}
+	for i := 1; i < len(a.Members); i++ {
+		if bytes.Compare(a.Members[i-1].Bytes(), a.Members[i].Bytes()) >= 0 {
+			return fmt.Errorf("multisig members are not sorted or not unique")
+		}
+	}
+	return nil
+}
+
+// Address returns the last 20 bytes of the keccak256 hash of the threshold
+// and the member addresses.
+func (a *MultiSigAccount) Address() (*types.Address, error) {
+	if err := a.check(); err != nil {
+		return nil, err
+	}
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(multiSigPrefix)
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(a.Threshold))
+	hash.Write(buf[:])
+	for _, member := range a.Members {
+		hash.Write(member.Bytes())
+	}
+	ret := hash.Sum(nil)
+
+	return types.NewAddress(ret[12:]), nil
+}
+
+// NewMultiSig returns an empty signature of the account.
+func (a *MultiSigAccount) NewMultiSig() *MultiSig {
+	members := make([][]byte, 0, len(a.Members))
+	for i := range a.Members {
+		members = append(members, append([]byte{}, a.Members[i].Bytes()...))
+	}
+	return &MultiSig{Threshold: a.Threshold, Members: members}
+}
+
+// Add adds the signature of a member, produced by SignWithType. A former
+// signature of the member is replaced.
+func (m *MultiSig) Add(pub crypto.PublicKey, sig []byte) error {
+	addr, err := pub.Address()
+	if err != nil {
+		return err
+	}
+	index := -1
+	for i, member := range m.Members {
+		if bytes.Equal(member, addr.Bytes()) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("%s is not a member of the multisig account", addr.String())
+	}
+
+	for i := range m.Sigs {
+		if m.Sigs[i].Index == index {
+			m.Sigs[i].Sig = sig
+			return nil
+		}
+	}
+	m.Sigs = append(m.Sigs, MultiSigItem{Index: index, Sig: sig})
+	sort.Slice(m.Sigs, func(i, j int) bool { return m.Sigs[i].Index < m.Sigs[j].Index })
+	return nil
+}
+
+// Marshal encodes the signature for VerifyMulti.
+func (m *MultiSig) Marshal() ([]byte, error) {
+	return asn1.Marshal(*m)
+}
+
+// UnmarshalMultiSig decodes a signature encoded by MultiSig.Marshal.
+func UnmarshalMultiSig(data []byte) (*MultiSig, error) {
+	m := &MultiSig{}
+	rest, err := asn1.Unmarshal(data, m)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after multisig")
+	}
+	return m, nil
+}
+
+// Account returns the account the signature belongs to.
+func (m *MultiSig) Account() (*MultiSigAccount, error) {
+	members := make([]types.Address, 0, len(m.Members))
+	for _, member := range m.Members {
+		if len(member) != types.AddressLength {
+			return nil, fmt.Errorf("invalid multisig member length %d", len(member))
+		}
+		members = append(members, *types.NewAddress(member))
+	}
+
+	account := &MultiSigAccount{Threshold: m.Threshold, Members: members}
+	if err := account.check(); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// VerifyMulti checks that sig is a signature of the multisig account with
+// address addr, signed by at least threshold members. Any invalid member
+// signature fails the verification.
+func VerifyMulti(sig, digest []byte, addr types.Address) (bool, error) {
+	m, err := UnmarshalMultiSig(sig)
+	if err != nil {
+		return false, err
+	}
+	account, err := m.Account()
+	if err != nil {
+		return false, err
+	}
+
+	expected, err := account.Address()
+	if err != nil {
+		return false, err
+	}
+	if expected.String() != addr.String() {
+		return false, fmt.Errorf("wrong multisig account for this signature")
+	}
+
+	signed := make(map[int]bool)
+	for _, item := range m.Sigs {
+		if item.Index < 0 || item.Index >= len(account.Members) {
+			return false, fmt.Errorf("invalid multisig member index %d", item.Index)
+		}
+		if signed[item.Index] {
+			return false, fmt.Errorf("duplicate signature of multisig member %d", item.Index)
+		}
+		if len(item.Sig) == 0 {
+			return false, fmt.Errorf("empty signature of multisig member %d", item.Index)
+		}
+		ok, err := VerifyWithType(item.Sig, digest, account.Members[item.Index])
+		if err != nil {
+			return false, fmt.Errorf("invalid signature of multisig member %d: %w", item.Index, err)
+		}
+		if !ok {
+			return false, fmt.Errorf("invalid signature of multisig member %d", item.Index)
+		}
+		signed[item.Index] = true
+	}
+
+	if len(signed) < account.Threshold {
+		return false, fmt.Errorf("multisig has %d of %d required signatures", len(signed), account.Threshold)
+	}
+	return true, nil
+}
@@ -0,0 +1,113 @@
+package asym
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiSig(t *testing.T) {
+	digest := sha256.Sum256([]byte("hyperchain"))
+
+	var (
+		privs []crypto.PrivateKey
+		pubs  []crypto.PublicKey
+	)
+	for _, opt := range []crypto.KeyType{crypto.Secp256k1, crypto.Ed25519, crypto.ECDSA_P256} {
+		priv, err := GenerateKeyPair(opt)
+		require.Nil(t, err)
+		privs = append(privs, priv)
+		pubs = append(pubs, priv.PublicKey())
+	}
+
+	account, err := NewMultiSigAccount(2, pubs)
+	require.Nil(t, err)
+	addr, err := account.Address()
+	require.Nil(t, err)
+
+	// The order of the keys doesn't matter, the threshold does
+	reversed, err := NewMultiSigAccount(2, []crypto.PublicKey{pubs[2], pubs[1], pubs[0]})
+	require.Nil(t, err)
+	reversedAddr, err := reversed.Address()
+	require.Nil(t, err)
+	require.Equal(t, addr, reversedAddr)
+	other, err := NewMultiSigAccount(3, pubs)
+	require.Nil(t, err)
+	otherAddr, err := other.Address()
+	require.Nil(t, err)
+	require.NotEqual(t, addr, otherAddr)
+
+	ms := account.NewMultiSig()
+	sig, err := SignWithType(privs[0], digest[:])
+	require.Nil(t, err)
+	require.Nil(t, ms.Add(pubs[0], sig))
+
+	data, err := ms.Marshal()
+	require.Nil(t, err)
+	ok, err := VerifyMulti(data, digest[:], *addr)
+	require.NotNil(t, err)
+	require.False(t, ok)
+
+	sig, err = SignWithType(privs[2], digest[:])
+	require.Nil(t, err)
+	require.Nil(t, ms.Add(pubs[2], sig))
+
+	data, err = ms.Marshal()
+	require.Nil(t, err)
+	ok, err = VerifyMulti(data, digest[:], *addr)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	ok, err = VerifyMulti(data, digest[:], *otherAddr)
+	require.NotNil(t, err)
+	require.False(t, ok)
+
+	wrongDigest := sha256.Sum256([]byte("hypercha1n"))
+	ok, err = VerifyMulti(data, wrongDigest[:], *addr)
+	require.NotNil(t, err)
+	require.False(t, ok)
+
+	// A signature of one member can't count for another
+	ms.Sigs[1].Sig = ms.Sigs[0].Sig
+	data, err = ms.Marshal()
+	require.Nil(t, err)
+	ok, err = VerifyMulti(data, digest[:], *addr)
+	require.NotNil(t, err)
+	require.False(t, ok)
+
+	// A verifier may reject a signature without an error
+	const typ = crypto.KeyType(100)
+	RegisterCrypto(typ, nil, func(opt crypto.KeyType, sig, digest []byte, from types.Address) (bool, error) {
+		return false, nil
+	}, nil)
+	defer delete(CryptoM, typ)
+	ms.Sigs[1].Sig = []byte{byte(typ)}
+	data, err = ms.Marshal()
+	require.Nil(t, err)
+	ok, err = VerifyMulti(data, digest[:], *addr)
+	require.EqualError(t, err, fmt.Sprintf("invalid signature of multisig member %d", ms.Sigs[1].Index))
+	require.False(t, ok)
+
+	outsider, err := GenerateKeyPair(crypto.Ed25519)
+	require.Nil(t, err)
+	require.NotNil(t, ms.Add(outsider.PublicKey(), sig))
+}
+
+func TestMultiSigAccountCheck(t *testing.T) {
+	priv, err := GenerateKeyPair(crypto.Ed25519)
+	require.Nil(t, err)
+
+	_, err = NewMultiSigAccount(1, []crypto.PublicKey{priv.PublicKey(), priv.PublicKey()})
+	require.NotNil(t, err)
+	_, err = NewMultiSigAccount(2, []crypto.PublicKey{priv.PublicKey()})
+	require.NotNil(t, err)
+	_, err = NewMultiSigAccount(0, []crypto.PublicKey{priv.PublicKey()})
+	require.NotNil(t, err)
+
+	_, err = VerifyMulti([]byte("not a multisig"), nil, types.Address{})
+	require.NotNil(t, err)
+}
@@ -0,0 +1,203 @@
+package asym
+
+import (
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/types"
+	"golang.org/x/crypto/sha3"
+)
+
+// rotationPrefix separates rotation certificates from other signed digests
+var rotationPrefix = []byte("bitxhub-key-rotation")
+
+// RotationCert hands the identity of an old key over to a new key. It is
+// signed by the old key and takes effect from block Height and unix Time,
+// a zero value doesn't restrict.
+type RotationCert struct {
+	Old     []byte
+	NewType int
+	NewKey  []byte
+	Height  int64
+	Time    int64
+	Sig     []byte
+}
+
+// NewRotationCert rotates the identity of old to newKey from the given height
+// and unix time on.
+func NewRotationCert(old crypto.PrivateKey, newKey crypto.PublicKey, height, timestamp int64) (*RotationCert, error) {
+	if height < 0 || timestamp < 0 {
+		return nil, fmt.Errorf("invalid rotation height %d or time %d", height, timestamp)
+	}
+	oldAddr, err := old.PublicKey().Address()
+	if err != nil {
+		return nil, err
+	}
+	newKeyBytes, err := newKey.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &RotationCert{
+		Old:     append([]byte{}, oldAddr.Bytes()...),
+		NewType: int(newKey.Type()),
+		NewKey:  newKeyBytes,
+		Height:  height,
+		Time:    timestamp,
+	}
+	c.Sig, err = SignWithType(old, c.digest())
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// digest is the keccak256 hash of everything but the signature
+func (c *RotationCert) digest() []byte {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(rotationPrefix)
+	hash.Write(c.Old)
+	var buf [8]byte
+	binary.BigEndian.PutUint32(buf[:4], uint32(c.NewType))
+	hash.Write(buf[:4])
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(c.NewKey)))
+	hash.Write(buf[:4])
+	hash.Write(c.NewKey)
+	binary.BigEndian.PutUint64(buf[:], uint64(c.Height))
+	hash.Write(buf[:])
+	binary.BigEndian.PutUint64(buf[:], uint64(c.Time))
+	hash.Write(buf[:])
+	return hash.Sum(nil)
+}
+
+// Marshal encodes the certificate.
+func (c *RotationCert) Marshal() ([]byte, error) {
+	return asn1.Marshal(*c)
+}
+
+// UnmarshalRotationCert decodes a certificate encoded by RotationCert.Marshal.
+func UnmarshalRotationCert(data []byte) (*RotationCert, error) {
+	c := &RotationCert{}
+	rest, err := asn1.Unmarshal(data, c)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after rotation certificate")
+	}
+	return c, nil
+}
+
+// PublicKey returns the new key.
+func (c *RotationCert) PublicKey() (crypto.PublicKey, error) {
+	return UnmarshalPublicKey(c.NewKey, crypto.KeyType(c.NewType))
+}
+
+// Verify checks that the certificate is signed by the old key and returns the
+// address of the new key.
+func (c *RotationCert) Verify() (*types.Address, error) {
+	if len(c.Old) != types.AddressLength {
+		return nil, fmt.Errorf("invalid rotation old address length %d", len(c.Old))
+	}
+	if c.Height < 0 || c.Time < 0 {
+		return nil, fmt.Errorf("invalid rotation height %d or time %d", c.Height, c.Time)
+	}
+	ok, err := VerifyWithType(c.Sig, c.digest(), *types.NewAddress(c.Old))
+	if err != nil {
+		return nil, fmt.Errorf("invalid rotation signature: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid rotation signature")
+	}
+
+	pub, err := c.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return pub.Address()
+}
+
+// activeAt reports whether the certificate has taken effect at the height and
+// time
+func (c *RotationCert) activeAt(height, timestamp int64) bool {
+	return height >= c.Height && timestamp >= c.Time
+}
+
+// VerifyRotationChain checks that every certificate is signed by the key the
+// previous one rotated to, starting with the root key, and returns the
+// address of the last key. Heights and times must not decrease along the
+// chain and a key can't appear twice.
+//
+// The chain only proves that each key handed over to the next, not that it
+// is the chain the identity actually took. Whoever holds a retired key can
+// still sign a certificate from it to a key of their own and present a fork
+// that verifies as well. Callers must take the certificates from a canonical
+// source, such as the ledger, and not from the party that is being verified.
+func VerifyRotationChain(root types.Address, certs []*RotationCert) (*types.Address, error) {
+	addrs, err := verifyRotationChain(root, certs)
+	if err != nil {
+		return nil, err
+	}
+	return &addrs[len(addrs)-1], nil
+}
+
+// RotatedAddress returns the address of the key which acted for the root
+// identity at the given height and unix time. The certificates must come from
+// a canonical source, see VerifyRotationChain.
+func RotatedAddress(root types.Address, certs []*RotationCert, height, timestamp int64) (*types.Address, error) {
+	addrs, err := verifyRotationChain(root, certs)
+	if err != nil {
+		return nil, err
+	}
+
+	i := 0
+	for i < len(certs) && certs[i].activeAt(height, timestamp) {
+		i++
+	}
+	return &addrs[i], nil
+}
+
+// VerifyRotated checks a signature produced by SignWithType at the given
+// height and unix time, by the key which acted for the root identity then.
+func VerifyRotated(sig, digest []byte, root types.Address, certs []*RotationCert, height, timestamp int64) (bool, error) {
+	addr, err := RotatedAddress(root, certs, height, timestamp)
+	if err != nil {
+		return false, err
+	}
+
+	return VerifyWithType(sig, digest, *addr)
+}
+
+// verifyRotationChain returns the addresses of the root and all rotated keys
+func verifyRotationChain(root types.Address, certs []*RotationCert) ([]types.Address, error) {
+	addrs := []types.Address{*types.NewAddress(root.Bytes())}
+	seen := map[types.Address]bool{addrs[0]: true}
+	for i, c := range certs {
+		if c == nil {
+			return nil, fmt.Errorf("rotation certificate %d is empty", i)
+		}
+		prev := addrs[len(addrs)-1]
+		if types.NewAddress(c.Old).String() != prev.String() {
+			return nil, fmt.Errorf("rotation certificate %d is not issued by %s", i, prev.String())
+		}
+		if i > 0 && (c.Height < certs[i-1].Height || c.Time < certs[i-1].Time) {
+			return nil, fmt.Errorf("rotation certificate %d takes effect before its predecessor", i)
+		}
+
+		addr, err := c.Verify()
+		if err != nil {
+			return nil, fmt.Errorf("rotation certificate %d: %w", i, err)
+		}
+		next := *types.NewAddress(addr.Bytes())
+		if seen[next] {
+			return nil, fmt.Errorf("rotation certificate %d rotates to a former key %s", i, next.String())
+		}
+		seen[next] = true
+		addrs = append(addrs, next)
+	}
+
+	return addrs, nil
+}
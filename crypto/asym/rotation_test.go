@@ -0,0 +1,84 @@
+package asym
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotation(t *testing.T) {
+	digest := sha256.Sum256([]byte("hyperchain"))
+
+	var privs []crypto.PrivateKey
+	for _, opt := range []crypto.KeyType{crypto.Secp256k1, crypto.Ed25519, crypto.SM2} {
+		priv, err := GenerateKeyPair(opt)
+		require.Nil(t, err)
+		privs = append(privs, priv)
+	}
+	root, err := privs[0].PublicKey().Address()
+	require.Nil(t, err)
+
+	first, err := NewRotationCert(privs[0], privs[1].PublicKey(), 10, 0)
+	require.Nil(t, err)
+	second, err := NewRotationCert(privs[1], privs[2].PublicKey(), 20, 0)
+	require.Nil(t, err)
+
+	data, err := second.Marshal()
+	require.Nil(t, err)
+	second, err = UnmarshalRotationCert(data)
+	require.Nil(t, err)
+	certs := []*RotationCert{first, second}
+
+	last, err := VerifyRotationChain(*root, certs)
+	require.Nil(t, err)
+	expected, err := privs[2].PublicKey().Address()
+	require.Nil(t, err)
+	require.Equal(t, expected.String(), last.String())
+
+	// Every key is accepted only while it acts for the root identity
+	for i, height := range []int64{5, 15, 25} {
+		for j, priv := range privs {
+			sig, err := SignWithType(priv, digest[:])
+			require.Nil(t, err)
+			ok, _ := VerifyRotated(sig, digest[:], *root, certs, height, 0)
+			require.Equal(t, i == j, ok, "height %d key %d", height, j)
+		}
+	}
+
+	// Times work like heights
+	timed, err := NewRotationCert(privs[0], privs[1].PublicKey(), 0, 1000)
+	require.Nil(t, err)
+	addr, err := RotatedAddress(*root, []*RotationCert{timed}, 100, 999)
+	require.Nil(t, err)
+	require.Equal(t, root.String(), addr.String())
+	addr, err = RotatedAddress(*root, []*RotationCert{timed}, 100, 1000)
+	require.Nil(t, err)
+	require.Equal(t, first.Old, timed.Old)
+	newAddr, err := privs[1].PublicKey().Address()
+	require.Nil(t, err)
+	require.Equal(t, newAddr.String(), addr.String())
+
+	// A tampered certificate, a broken or out of order chain and a rotation
+	// back to a former key are rejected
+	tampered := *first
+	tampered.Height = 1
+	_, err = VerifyRotationChain(*root, []*RotationCert{&tampered, second})
+	require.NotNil(t, err)
+	_, err = VerifyRotationChain(*root, []*RotationCert{second})
+	require.NotNil(t, err)
+	early, err := NewRotationCert(privs[1], privs[2].PublicKey(), 5, 0)
+	require.Nil(t, err)
+	_, err = VerifyRotationChain(*root, []*RotationCert{first, early})
+	require.NotNil(t, err)
+	back, err := NewRotationCert(privs[1], privs[0].PublicKey(), 20, 0)
+	require.Nil(t, err)
+	_, err = VerifyRotationChain(*root, []*RotationCert{first, back})
+	require.NotNil(t, err)
+
+	// Without certificates the root key is the only one
+	addr, err = VerifyRotationChain(*root, nil)
+	require.Nil(t, err)
+	require.Equal(t, root.String(), addr.String())
+}
@@ -0,0 +1,305 @@
+package rsa
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+
+	crypto2 "github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/types"
+	"golang.org/x/crypto/sha3"
+)
+
+var _ crypto2.PrivateKey = (*PrivateKey)(nil)
+var _ crypto2.PublicKey = (*PublicKey)(nil)
+
+const (
+	// DefaultBits is the key size used by asym.GenerateKeyPair
+	DefaultBits = 2048
+
+	// MinBits is the smallest key size New accepts
+	MinBits = 2048
+)
+
+// Scheme is the RSA signature scheme
+type Scheme int
+
+const (
+	PSS Scheme = iota
+	PKCS1v15
+)
+
+// PrivateKey RSA private key.
+// never new(PrivateKey), use New()
+type PrivateKey struct {
+	K      *rsa.PrivateKey
+	scheme Scheme
+}
+
+// PublicKey RSA public key.
+type PublicKey struct {
+	K *rsa.PublicKey
+}
+
+// Sig holds a RSA signature together with the signing public key and scheme
+type Sig struct {
+	Scheme int    `json:"scheme"`
+	Pub    []byte `json:"pub"`
+	Sig    []byte `json:"sig"`
+}
+
+// New generates a rsa private key of the given size, which signs with PSS
+func New(bits int) (*PrivateKey, error) {
+	if bits < MinBits {
+		return nil, fmt.Errorf("rsa key size %d is less than %d", bits, MinBits)
+	}
+
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrivateKey{K: priv, scheme: PSS}, nil
+}
+
+func NewWithCryptoKey(priv *rsa.PrivateKey) (*PrivateKey, error) {
+	if priv == nil {
+		return nil, fmt.Errorf("empty rsa private key")
+	}
+
+	return &PrivateKey{K: priv, scheme: PSS}, nil
+}
+
+func NewPublicKey(pub *rsa.PublicKey) (*PublicKey, error) {
+	if pub == nil {
+		return nil, fmt.Errorf("empty rsa public key")
+	}
+
+	return &PublicKey{K: pub}, nil
+}
+
+// SetScheme sets the scheme Sign uses
+func (priv *PrivateKey) SetScheme(scheme Scheme) error {
+	if _, err := schemeOf(int(scheme)); err != nil {
+		return err
+	}
+	priv.scheme = scheme
+	return nil
+}
+
+// Bytes returns the PKCS#1 DER encoding of the private key
+func (priv *PrivateKey) Bytes() ([]byte, error) {
+	if priv.K == nil {
+		return nil, fmt.Errorf("RSAPrivateKey.K is nil")
+	}
+
+	return x509.MarshalPKCS1PrivateKey(priv.K), nil
+}
+
+// PKCS8Bytes returns the PKCS#8 DER encoding of the private key
+func (priv *PrivateKey) PKCS8Bytes() ([]byte, error) {
+	if priv.K == nil {
+		return nil, fmt.Errorf("RSAPrivateKey.K is nil")
+	}
+
+	return x509.MarshalPKCS8PrivateKey(priv.K)
+}
+
+func (priv *PrivateKey) PublicKey() crypto2.PublicKey {
+	return &PublicKey{K: &priv.K.PublicKey}
+}
+
+// Sign signs digest with the scheme of the key and wraps the signature into
+// an ASN.1 Sig together with the public key.
+func (priv *PrivateKey) Sign(digest []byte) ([]byte, error) {
+	sig, err := priv.SignWithScheme(priv.scheme, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	pubBytes, err := priv.PublicKey().Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(Sig{Scheme: int(priv.scheme), Pub: pubBytes, Sig: sig})
+}
+
+// SignWithScheme returns the raw signature of digest. The hash function is
+// chosen by the digest length: SHA-256, SHA-384 or SHA-512.
+func (priv *PrivateKey) SignWithScheme(scheme Scheme, digest []byte) ([]byte, error) {
+	if priv.K == nil {
+		return nil, fmt.Errorf("RSAPrivateKey.K is nil")
+	}
+
+	hash, err := hashOf(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	switch scheme {
+	case PSS:
+		return rsa.SignPSS(rand.Reader, priv.K, hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case PKCS1v15:
+		return rsa.SignPKCS1v15(rand.Reader, priv.K, hash, digest)
+	default:
+		return nil, fmt.Errorf("unsupported rsa signature scheme %d", scheme)
+	}
+}
+
+func (priv *PrivateKey) Type() crypto2.KeyType {
+	return crypto2.RSA
+}
+
+// UnmarshalPrivateKey parses a PKCS#1 or PKCS#8 DER encoded private key
+func UnmarshalPrivateKey(data []byte) (*PrivateKey, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty private key data")
+	}
+
+	if priv, err := x509.ParsePKCS1PrivateKey(data); err == nil {
+		return &PrivateKey{K: priv, scheme: PSS}, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("not supported format: %w", err)
+	}
+
+	priv, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not rsa private key")
+	}
+
+	return &PrivateKey{K: priv, scheme: PSS}, nil
+}
+
+// UnmarshalPublicKey parses a PKIX or PKCS#1 DER encoded public key
+func UnmarshalPublicKey(data []byte) (*PublicKey, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty public key data")
+	}
+
+	if pub, err := x509.ParsePKCS1PublicKey(data); err == nil {
+		return &PublicKey{K: pub}, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("not supported format: %w", err)
+	}
+
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("not rsa public key")
+	}
+
+	return &PublicKey{K: pub}, nil
+}
+
+// Bytes returns the PKIX DER encoding of the public key
+func (pub *PublicKey) Bytes() ([]byte, error) {
+	if pub.K == nil {
+		return nil, fmt.Errorf("RSAPublicKey.K is nil")
+	}
+
+	return x509.MarshalPKIXPublicKey(pub.K)
+}
+
+// Address returns the last 20 bytes of the keccak256 hash of the PKCS#1
+// encoded public key.
+func (pub *PublicKey) Address() (*types.Address, error) {
+	if pub.K == nil {
+		return nil, fmt.Errorf("RSAPublicKey.K is nil")
+	}
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(x509.MarshalPKCS1PublicKey(pub.K))
+	ret := hash.Sum(nil)
+
+	return types.NewAddress(ret[12:]), nil
+}
+
+// Verify checks a signature produced by PrivateKey.Sign
+func (pub *PublicKey) Verify(digest []byte, sig []byte) (bool, error) {
+	if sig == nil {
+		return false, fmt.Errorf("nil signature")
+	}
+
+	sigStruct := &Sig{}
+	if _, err := asn1.Unmarshal(sig, sigStruct); err != nil {
+		return false, err
+	}
+
+	scheme, err := schemeOf(sigStruct.Scheme)
+	if err != nil {
+		return false, err
+	}
+
+	return pub.VerifyWithScheme(scheme, digest, sigStruct.Sig)
+}
+
+// VerifyWithScheme checks a raw signature, e.g. one produced by a legacy system
+func (pub *PublicKey) VerifyWithScheme(scheme Scheme, digest []byte, sig []byte) (bool, error) {
+	if pub.K == nil {
+		return false, fmt.Errorf("RSAPublicKey.K is nil")
+	}
+
+	hash, err := hashOf(digest)
+	if err != nil {
+		return false, err
+	}
+
+	switch scheme {
+	case PSS:
+		err = rsa.VerifyPSS(pub.K, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+	case PKCS1v15:
+		err = rsa.VerifyPKCS1v15(pub.K, hash, digest, sig)
+	default:
+		return false, fmt.Errorf("unsupported rsa signature scheme %d", scheme)
+	}
+	if err != nil {
+		return false, fmt.Errorf("invalid signature")
+	}
+
+	return true, nil
+}
+
+func (pub *PublicKey) Type() crypto2.KeyType {
+	return crypto2.RSA
+}
+
+// SigToPub returns the public key embedded in a signature produced by Sign
+func SigToPub(sig []byte) (*PublicKey, error) {
+	sigStruct := &Sig{}
+	if _, err := asn1.Unmarshal(sig, sigStruct); err != nil {
+		return nil, err
+	}
+
+	return UnmarshalPublicKey(sigStruct.Pub)
+}
+
+func schemeOf(scheme int) (Scheme, error) {
+	switch Scheme(scheme) {
+	case PSS, PKCS1v15:
+		return Scheme(scheme), nil
+	default:
+		return 0, fmt.Errorf("unsupported rsa signature scheme %d", scheme)
+	}
+}
+
+func hashOf(digest []byte) (crypto.Hash, error) {
+	switch len(digest) {
+	case crypto.SHA256.Size():
+		return crypto.SHA256, nil
+	case crypto.SHA384.Size():
+		return crypto.SHA384, nil
+	case crypto.SHA512.Size():
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported digest length %d", len(digest))
+	}
+}
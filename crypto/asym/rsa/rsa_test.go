@@ -0,0 +1,98 @@
+package rsa
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	priv, err := New(DefaultBits)
+	require.Nil(t, err)
+
+	for _, scheme := range []Scheme{PSS, PKCS1v15} {
+		require.Nil(t, priv.SetScheme(scheme))
+
+		digest := sha256.Sum256([]byte("hyperchain"))
+		sig, err := priv.Sign(digest[:])
+		require.Nil(t, err)
+		b, err := priv.PublicKey().Verify(digest[:], sig)
+		require.Nil(t, err)
+		require.True(t, b)
+
+		wrongDigest := sha256.Sum256([]byte("hypercha1n"))
+		b, err = priv.PublicKey().Verify(wrongDigest[:], sig)
+		require.NotNil(t, err)
+		require.False(t, b)
+
+		digest512 := sha512.Sum512([]byte("hyperchain"))
+		sig, err = priv.Sign(digest512[:])
+		require.Nil(t, err)
+		b, err = priv.PublicKey().Verify(digest512[:], sig)
+		require.Nil(t, err)
+		require.True(t, b)
+	}
+
+	require.NotNil(t, priv.SetScheme(Scheme(5)))
+	_, err = New(1024)
+	require.NotNil(t, err)
+}
+
+func TestVerifyLegacySignature(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	pub, err := NewPublicKey(&key.PublicKey)
+	require.Nil(t, err)
+
+	digest := sha256.Sum256([]byte("hyperchain"))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.Nil(t, err)
+	b, err := pub.VerifyWithScheme(PKCS1v15, digest[:], sig)
+	require.Nil(t, err)
+	require.True(t, b)
+
+	sig, err = rsa.SignPSS(rand.Reader, key, crypto.SHA256, digest[:], nil)
+	require.Nil(t, err)
+	b, err = pub.VerifyWithScheme(PSS, digest[:], sig)
+	require.Nil(t, err)
+	require.True(t, b)
+
+	_, err = pub.VerifyWithScheme(PKCS1v15, digest[:], sig)
+	require.NotNil(t, err)
+}
+
+func TestMarshal(t *testing.T) {
+	priv, err := New(DefaultBits)
+	require.Nil(t, err)
+
+	pkcs1, err := priv.Bytes()
+	require.Nil(t, err)
+	restored, err := UnmarshalPrivateKey(pkcs1)
+	require.Nil(t, err)
+	require.Equal(t, priv.K.D, restored.K.D)
+
+	pkcs8, err := priv.PKCS8Bytes()
+	require.Nil(t, err)
+	restored, err = UnmarshalPrivateKey(pkcs8)
+	require.Nil(t, err)
+	require.Equal(t, priv.K.D, restored.K.D)
+
+	_, err = UnmarshalPrivateKey([]byte("rsa"))
+	require.NotNil(t, err)
+
+	pubBytes, err := priv.PublicKey().Bytes()
+	require.Nil(t, err)
+	pub, err := UnmarshalPublicKey(pubBytes)
+	require.Nil(t, err)
+
+	addr1, err := priv.PublicKey().Address()
+	require.Nil(t, err)
+	addr2, err := pub.Address()
+	require.Nil(t, err)
+	require.Equal(t, addr1, addr2)
+}
@@ -0,0 +1,137 @@
+package schnorr
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/bits"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// BatchVerify checks the signatures sigs[i] of digests[i] by pubs[i] at once,
+// with the batch verification algorithm of BIP340. The verification
+// equations are combined with random weights and checked with a single
+// multi-scalar multiplication. If it fails, at least one signature is invalid
+// and the signatures need to be checked one by one to find it.
+func BatchVerify(pubs []*PublicKey, digests [][]byte, sigs [][]byte) (bool, error) {
+	if len(pubs) == 0 || len(pubs) != len(digests) || len(pubs) != len(sigs) {
+		return false, fmt.Errorf("public keys, digests and signatures don't match")
+	}
+
+	var (
+		scalars = make([]secp256k1.ModNScalar, 0, 2*len(pubs))
+		points  = make([]secp256k1.JacobianPoint, 0, 2*len(pubs))
+		sum     secp256k1.ModNScalar
+		buf     = make([]byte, 16)
+	)
+	for i, pub := range pubs {
+		pubBytes := toBytes(pub.x)
+		sig, err := rawSignature(pubBytes, sigs[i])
+		if err != nil {
+			return false, err
+		}
+
+		var (
+			as, s, e, a secp256k1.ModNScalar
+			rPoint      secp256k1.JacobianPoint
+			pubPoint    secp256k1.JacobianPoint
+		)
+		// R is the point with x = r and an even y, r < p and s < n
+		if rPoint.X.SetByteSlice(sig[:32]) || !secp256k1.DecompressY(&rPoint.X, false, &rPoint.Y) {
+			return false, fmt.Errorf("invalid signature")
+		}
+		rPoint.Y.Normalize()
+		rPoint.Z.SetInt(1)
+		if s.SetByteSlice(sig[32:]) {
+			return false, fmt.Errorf("invalid signature")
+		}
+		pubPoint.X.SetByteSlice(pubBytes)
+		pubPoint.Y.SetByteSlice(toBytes(pub.y))
+		pubPoint.Z.SetInt(1)
+		e.SetByteSlice(toBytes(challenge(sig[:32], pubBytes, digests[i])))
+
+		// The first weight is 1, the others are random 128 bit numbers
+		if i == 0 {
+			a.SetInt(1)
+		} else {
+			for a.IsZero() {
+				if _, err := rand.Read(buf); err != nil {
+					return false, err
+				}
+				a.SetByteSlice(buf)
+			}
+		}
+
+		sum.Add(as.Mul2(&a, &s))
+		scalars = append(scalars, a, *e.Mul(&a))
+		points = append(points, rPoint, pubPoint)
+	}
+
+	// (s1 + a2*s2 + ... + au*su)*G == R1 + a2*R2 + ... + au*Ru +
+	// e1*P1 + (a2*e2)*P2 + ... + (au*eu)*Pu
+	var lhs secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&sum, &lhs)
+	rhs := multiScalarMult(scalars, points)
+	lhs.ToAffine()
+	rhs.ToAffine()
+	if !lhs.X.Equals(&rhs.X) || !lhs.Y.Equals(&rhs.Y) {
+		return false, fmt.Errorf("invalid signature in batch")
+	}
+	return true, nil
+}
+
+// multiScalarMult computes the sum of scalars[i]*points[i] with Pippenger's
+// bucket method: the scalars are split into windows of c bits, the points are
+// sorted into buckets by their digit in the window and the buckets are summed
+// up with their weights, which needs far fewer additions than multiplying
+// every point on its own.
+func multiScalarMult(scalars []secp256k1.ModNScalar, points []secp256k1.JacobianPoint) secp256k1.JacobianPoint {
+	c := uint(bits.Len(uint(len(points)))/2 + 2)
+	digits := make([][32]byte, len(scalars))
+	for i := range scalars {
+		digits[i] = scalars[i].Bytes()
+	}
+
+	var (
+		result  secp256k1.JacobianPoint
+		buckets = make([]secp256k1.JacobianPoint, 1<<c)
+	)
+	for start := int((256 + c - 1) / c * c); start > 0; {
+		start -= int(c)
+		for j := uint(0); j < c; j++ {
+			secp256k1.DoubleNonConst(&result, &result)
+		}
+
+		for j := range buckets {
+			buckets[j] = secp256k1.JacobianPoint{}
+		}
+		for i := range points {
+			if d := window(&digits[i], uint(start), c); d != 0 {
+				secp256k1.AddNonConst(&buckets[d], &points[i], &buckets[d])
+			}
+		}
+
+		// The running sum adds bucket d to the window sum d times
+		var running, sum secp256k1.JacobianPoint
+		for d := len(buckets) - 1; d > 0; d-- {
+			secp256k1.AddNonConst(&running, &buckets[d], &running)
+			secp256k1.AddNonConst(&sum, &running, &sum)
+		}
+		secp256k1.AddNonConst(&result, &sum, &result)
+	}
+	return result
+}
+
+// window returns the width bits of the big endian number b starting at bit
+// start
+func window(b *[32]byte, start, width uint) uint {
+	var d uint
+	for j := width; j > 0; j-- {
+		bit := start + j - 1
+		d <<= 1
+		if bit < 256 {
+			d |= uint(b[31-bit/8]>>(bit%8)) & 1
+		}
+	}
+	return d
+}
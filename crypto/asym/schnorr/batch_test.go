@@ -0,0 +1,89 @@
+package schnorr
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchVerify(t *testing.T) {
+	var (
+		pubs    = make([]*PublicKey, 20)
+		digests = make([][]byte, 20)
+		sigs    = make([][]byte, 20)
+	)
+	for i := range pubs {
+		priv, err := New()
+		require.Nil(t, err)
+		d := sha256.Sum256([]byte{byte(i)})
+		pubs[i] = priv.pub
+		digests[i] = d[:]
+		sigs[i], err = priv.Sign(digests[i])
+		require.Nil(t, err)
+	}
+	// Raw signatures and the same signature twice
+	sigs[1] = sigs[1][PublicKeyLength:]
+	pubs[2], digests[2], sigs[2] = pubs[3], digests[3], sigs[3]
+
+	ok, err := BatchVerify(pubs, digests, sigs)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	// The second BIP340 test vector
+	pub, err := UnmarshalPublicKey(decodeHex(t, "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659"))
+	require.Nil(t, err)
+	ok, err = BatchVerify(append(pubs, pub), append(digests, decodeHex(t, "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89")),
+		append(sigs, decodeHex(t, "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A")))
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	digests[0], digests[19] = digests[19], digests[0]
+	ok, err = BatchVerify(pubs, digests, sigs)
+	require.NotNil(t, err)
+	require.False(t, ok)
+	digests[0], digests[19] = digests[19], digests[0]
+
+	// s = n is rejected
+	bad := append([]byte{}, sigs[5]...)
+	copy(bad[PublicKeyLength+32:], toBytes(curve.N))
+	sigs[5] = bad
+	ok, err = BatchVerify(pubs, digests, sigs)
+	require.NotNil(t, err)
+	require.False(t, ok)
+
+	_, err = BatchVerify(pubs, digests[1:], sigs)
+	require.NotNil(t, err)
+}
+
+func TestMultiScalarMult(t *testing.T) {
+	for _, n := range []int{1, 3, 40} {
+		var (
+			scalars  = make([]secp256k1.ModNScalar, n)
+			points   = make([]secp256k1.JacobianPoint, n)
+			expected secp256k1.JacobianPoint
+		)
+		for i := range points {
+			d := sha256.Sum256([]byte{byte(i), byte(n)})
+			scalars[i].SetByteSlice(d[:])
+			k := new(secp256k1.ModNScalar).SetInt(uint32(i + 7))
+			secp256k1.ScalarBaseMultNonConst(k, &points[i])
+			points[i].ToAffine()
+
+			var p secp256k1.JacobianPoint
+			secp256k1.ScalarMultNonConst(&scalars[i], &points[i], &p)
+			secp256k1.AddNonConst(&expected, &p, &expected)
+		}
+		result := multiScalarMult(scalars, points)
+		result.ToAffine()
+		expected.ToAffine()
+		require.True(t, expected.X.Equals(&result.X), n)
+		require.True(t, expected.Y.Equals(&result.Y), n)
+	}
+
+	b := [32]byte{31: 0xb5, 30: 0x01}
+	require.Equal(t, uint(0x5), window(&b, 0, 3))
+	require.Equal(t, uint(0x1b), window(&b, 4, 5))
+	require.Equal(t, uint(0), window(&b, 254, 4))
+}
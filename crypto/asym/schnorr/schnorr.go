@@ -0,0 +1,313 @@
+// Package schnorr implements BIP340 Schnorr signatures over secp256k1 with
+// 32 byte x-only public keys. The bundled libsecp256k1 is built without the
+// schnorrsig module, so the package is assembled from the constant time
+// scalar arithmetic of dcrd's secp256k1, the curve of the ecdsa package for
+// multiplying secrets with the base point, btcec for verification and dcrd's
+// Jacobian arithmetic for batch verification.
+//
+// The ecdsa curve multiplies in constant time in libsecp256k1. Builds without
+// cgo fall back to btcec, whose multiplication is not constant time, so that
+// the timing of signing may leak information about the key.
+package schnorr
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/ecdsa"
+	"github.com/meshplus/bitxhub-kit/types"
+	"golang.org/x/crypto/sha3"
+)
+
+var _ crypto.PrivateKey = (*PrivateKey)(nil)
+var _ crypto.PublicKey = (*PublicKey)(nil)
+
+const (
+	PrivateKeyLength = 32
+	PublicKeyLength  = 32
+	// RawSignatureLength is the length of a BIP340 signature
+	RawSignatureLength = 64
+	// SignatureLength is the length of a signature produced by PrivateKey.Sign,
+	// the x-only public key followed by the BIP340 signature.
+	SignatureLength = PublicKeyLength + RawSignatureLength
+)
+
+var (
+	curve = btcec.S256()
+	// sqrtExp is (p+1)/4, p = 3 mod 4 so c^sqrtExp is a square root of c
+	sqrtExp = curve.QPlus1Div4()
+
+	tagAux       = tagHash("BIP0340/aux")
+	tagNonce     = tagHash("BIP0340/nonce")
+	tagChallenge = tagHash("BIP0340/challenge")
+)
+
+// PrivateKey BIP340 Schnorr private key.
+type PrivateKey struct {
+	d   *secp256k1.ModNScalar
+	pub *PublicKey
+}
+
+// PublicKey BIP340 Schnorr public key, the point with even y and the given x.
+type PublicKey struct {
+	x, y *big.Int
+}
+
+// New generates a BIP340 Schnorr private key
+func New() (*PrivateKey, error) {
+	buf := make([]byte, PrivateKeyLength)
+	for {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		if priv, err := UnmarshalPrivateKey(buf); err == nil {
+			return priv, nil
+		}
+	}
+}
+
+// UnmarshalPrivateKey parses a 32 bytes big endian secret key
+func UnmarshalPrivateKey(data []byte) (*PrivateKey, error) {
+	if len(data) != PrivateKeyLength {
+		return nil, fmt.Errorf("invalid schnorr private key length %d", len(data))
+	}
+	d := new(secp256k1.ModNScalar)
+	if d.SetByteSlice(data) || d.IsZero() {
+		return nil, fmt.Errorf("invalid schnorr private key")
+	}
+
+	x, y := ecdsa.S256().ScalarBaseMult(data)
+	return &PrivateKey{d: d, pub: &PublicKey{x: x, y: evenY(y)}}, nil
+}
+
+// UnmarshalPublicKey parses a 32 bytes x-only public key
+func UnmarshalPublicKey(data []byte) (*PublicKey, error) {
+	if len(data) != PublicKeyLength {
+		return nil, fmt.Errorf("invalid schnorr public key length %d", len(data))
+	}
+	x, y, err := liftX(new(big.Int).SetBytes(data))
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKey{x: x, y: y}, nil
+}
+
+// SigToPub returns the public key embedded in a signature produced by Sign.
+func SigToPub(sig []byte) (*PublicKey, error) {
+	if len(sig) != SignatureLength {
+		return nil, fmt.Errorf("invalid schnorr signature length %d", len(sig))
+	}
+
+	return UnmarshalPublicKey(sig[:PublicKeyLength])
+}
+
+func (priv *PrivateKey) Bytes() ([]byte, error) {
+	if priv.d == nil {
+		return nil, fmt.Errorf("SchnorrPrivateKey.d is nil")
+	}
+	b := priv.d.Bytes()
+	return b[:], nil
+}
+
+func (priv *PrivateKey) PublicKey() crypto.PublicKey {
+	return priv.pub
+}
+
+// Sign signs digest with fresh auxiliary randomness and prefixes the signature
+// with the x-only public key, since it can't be recovered from the signature.
+func (priv *PrivateKey) Sign(digest []byte) ([]byte, error) {
+	if priv.d == nil {
+		return nil, fmt.Errorf("SchnorrPrivateKey.d is nil")
+	}
+
+	aux := make([]byte, 32)
+	if _, err := rand.Read(aux); err != nil {
+		return nil, err
+	}
+	sig, err := priv.sign(digest, aux)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]byte, 0, SignatureLength)
+	ret = append(ret, toBytes(priv.pub.x)...)
+	return append(ret, sig...), nil
+}
+
+// sign computes the BIP340 signature of msg with auxiliary random data aux.
+func (priv *PrivateKey) sign(msg, aux []byte) ([]byte, error) {
+	d := *priv.d
+	defer d.Zero()
+	t := d.Bytes()
+	px, py := ecdsa.S256().ScalarBaseMult(t[:])
+	if py.Bit(0) == 1 {
+		d.Negate()
+	}
+	pubBytes := toBytes(px)
+
+	t = d.Bytes()
+	defer ecdsa.ZeroBytes(t[:])
+	auxHash := taggedHash(tagAux, aux)
+	for i := range t {
+		t[i] ^= auxHash[i]
+	}
+
+	var k secp256k1.ModNScalar
+	defer k.Zero()
+	k.SetByteSlice(taggedHash(tagNonce, t[:], pubBytes, msg))
+	if k.IsZero() {
+		return nil, fmt.Errorf("schnorr nonce is zero")
+	}
+	kBytes := k.Bytes()
+	defer ecdsa.ZeroBytes(kBytes[:])
+	rx, ry := ecdsa.S256().ScalarBaseMult(kBytes[:])
+	if ry.Bit(0) == 1 {
+		k.Negate()
+	}
+	rBytes := toBytes(rx)
+
+	// s = k + e*d
+	var e, sig secp256k1.ModNScalar
+	e.SetByteSlice(taggedHash(tagChallenge, rBytes, pubBytes, msg))
+	sig.Mul2(&e, &d).Add(&k)
+	sBytes := sig.Bytes()
+
+	return append(rBytes, sBytes[:]...), nil
+}
+
+func (priv *PrivateKey) Type() crypto.KeyType {
+	return crypto.Secp256k1Schnorr
+}
+
+// Bytes returns the 32 bytes x-only public key
+func (pub *PublicKey) Bytes() ([]byte, error) {
+	if pub.x == nil {
+		return nil, fmt.Errorf("SchnorrPublicKey.x is nil")
+	}
+	return toBytes(pub.x), nil
+}
+
+// Address returns the last 20 bytes of the keccak256 hash of the full
+// uncompressed point, which matches the address of the secp256k1 ECDSA key
+// with the same point.
+func (pub *PublicKey) Address() (*types.Address, error) {
+	if pub.x == nil {
+		return nil, fmt.Errorf("SchnorrPublicKey.x is nil")
+	}
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(toBytes(pub.x))
+	hash.Write(toBytes(pub.y))
+	ret := hash.Sum(nil)
+
+	return types.NewAddress(ret[12:]), nil
+}
+
+// Verify checks either a signature produced by Sign or a raw 64 bytes BIP340
+// signature.
+func (pub *PublicKey) Verify(digest []byte, sig []byte) (bool, error) {
+	if sig == nil {
+		return false, fmt.Errorf("nil signature")
+	}
+
+	pubBytes := toBytes(pub.x)
+	sig, err := rawSignature(pubBytes, sig)
+	if err != nil {
+		return false, err
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(curve.P) >= 0 || s.Cmp(curve.N) >= 0 {
+		return false, fmt.Errorf("invalid signature")
+	}
+
+	// R = s*G - e*P
+	e := challenge(sig[:32], pubBytes, digest)
+	sx, sy := curve.ScalarBaseMult(sig[32:])
+	ex, ey := curve.ScalarMult(pub.x, pub.y, toBytes(e))
+	ey.Sub(curve.P, ey)
+	rx, ry := curve.Add(sx, sy, ex, ey)
+
+	if (rx.Sign() == 0 && ry.Sign() == 0) || ry.Bit(0) == 1 || rx.Cmp(r) != 0 {
+		return false, fmt.Errorf("invalid signature")
+	}
+
+	return true, nil
+}
+
+func (pub *PublicKey) Type() crypto.KeyType {
+	return crypto.Secp256k1Schnorr
+}
+
+// rawSignature strips the public key off a signature produced by Sign
+func rawSignature(pubBytes, sig []byte) ([]byte, error) {
+	switch len(sig) {
+	case SignatureLength:
+		if !bytes.Equal(sig[:PublicKeyLength], pubBytes) {
+			return nil, fmt.Errorf("signature is signed by another public key")
+		}
+		return sig[PublicKeyLength:], nil
+	case RawSignatureLength:
+		return sig, nil
+	default:
+		return nil, fmt.Errorf("invalid schnorr signature length %d", len(sig))
+	}
+}
+
+// liftX returns the point with the given x and an even y
+func liftX(x *big.Int) (*big.Int, *big.Int, error) {
+	if x.Cmp(curve.P) >= 0 {
+		return nil, nil, fmt.Errorf("invalid schnorr public key")
+	}
+	c := new(big.Int).Exp(x, big.NewInt(3), curve.P)
+	c.Add(c, curve.B)
+	c.Mod(c, curve.P)
+	y := new(big.Int).Exp(c, sqrtExp, curve.P)
+	if new(big.Int).Exp(y, big.NewInt(2), curve.P).Cmp(c) != 0 {
+		return nil, nil, fmt.Errorf("invalid schnorr public key")
+	}
+	return new(big.Int).Set(x), evenY(y), nil
+}
+
+func evenY(y *big.Int) *big.Int {
+	if y.Bit(0) == 1 {
+		return new(big.Int).Sub(curve.P, y)
+	}
+	return y
+}
+
+func challenge(r, pub, msg []byte) *big.Int {
+	e := new(big.Int).SetBytes(taggedHash(tagChallenge, r, pub, msg))
+	return e.Mod(e, curve.N)
+}
+
+func tagHash(tag string) []byte {
+	h := sha256.Sum256([]byte(tag))
+	return h[:]
+}
+
+// taggedHash computes sha256(sha256(tag) || sha256(tag) || data...)
+func taggedHash(tag []byte, data ...[]byte) []byte {
+	h := sha256.New()
+	h.Write(tag)
+	h.Write(tag)
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// toBytes returns n as a 32 bytes big endian integer
+func toBytes(n *big.Int) []byte {
+	b := n.Bytes()
+	ret := make([]byte, 32)
+	copy(ret[32-len(b):], b)
+	return ret
+}
@@ -0,0 +1,133 @@
+package schnorr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/ecdsa"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	data, err := hex.DecodeString(s)
+	require.Nil(t, err)
+	return data
+}
+
+// Test vectors from BIP340
+func TestBIP340Vectors(t *testing.T) {
+	vectors := []struct {
+		secret string
+		pubkey string
+		aux    string
+		msg    string
+		sig    string
+	}{
+		{
+			secret: "0000000000000000000000000000000000000000000000000000000000000003",
+			pubkey: "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+			aux:    "0000000000000000000000000000000000000000000000000000000000000000",
+			msg:    "0000000000000000000000000000000000000000000000000000000000000000",
+			sig:    "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+		},
+		{
+			secret: "B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF",
+			pubkey: "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+			aux:    "0000000000000000000000000000000000000000000000000000000000000001",
+			msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+			sig:    "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+		},
+	}
+
+	for _, v := range vectors {
+		priv, err := UnmarshalPrivateKey(decodeHex(t, v.secret))
+		require.Nil(t, err)
+		pubData, err := priv.PublicKey().Bytes()
+		require.Nil(t, err)
+		require.Equal(t, v.pubkey, strings.ToUpper(hex.EncodeToString(pubData)))
+
+		msg := decodeHex(t, v.msg)
+		sig, err := priv.sign(msg, decodeHex(t, v.aux))
+		require.Nil(t, err)
+		require.Equal(t, v.sig, strings.ToUpper(hex.EncodeToString(sig)))
+
+		pub, err := UnmarshalPublicKey(pubData)
+		require.Nil(t, err)
+		b, err := pub.Verify(msg, sig)
+		require.Nil(t, err)
+		require.True(t, b)
+
+		sig[63] ^= 1
+		b, err = pub.Verify(msg, sig)
+		require.NotNil(t, err)
+		require.False(t, b)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	digest := sha256.Sum256([]byte("hyperchain"))
+	priv, err := New()
+	require.Nil(t, err)
+
+	sig, err := priv.Sign(digest[:])
+	require.Nil(t, err)
+	require.Equal(t, SignatureLength, len(sig))
+
+	pub, err := SigToPub(sig)
+	require.Nil(t, err)
+	require.Equal(t, priv.PublicKey(), pub)
+
+	b, err := pub.Verify(digest[:], sig)
+	require.Nil(t, err)
+	require.True(t, b)
+
+	b, err = pub.Verify(digest[:], sig[PublicKeyLength:])
+	require.Nil(t, err)
+	require.True(t, b)
+
+	wrongDigest := sha256.Sum256([]byte("hypercha1n"))
+	b, err = pub.Verify(wrongDigest[:], sig)
+	require.NotNil(t, err)
+	require.False(t, b)
+
+	other, err := New()
+	require.Nil(t, err)
+	_, err = other.PublicKey().Verify(digest[:], sig)
+	require.NotNil(t, err)
+}
+
+func TestMarshal(t *testing.T) {
+	priv, err := New()
+	require.Nil(t, err)
+
+	data, err := priv.Bytes()
+	require.Nil(t, err)
+	restored, err := UnmarshalPrivateKey(data)
+	require.Nil(t, err)
+	require.Equal(t, priv, restored)
+
+	_, err = UnmarshalPrivateKey(make([]byte, PrivateKeyLength))
+	require.NotNil(t, err)
+
+	// public key of BIP340 test vector 5, which is not on the curve
+	_, err = UnmarshalPublicKey(decodeHex(t, "EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34"))
+	require.NotNil(t, err)
+}
+
+func TestAddress(t *testing.T) {
+	// secret 3 has an even y, so the ecdsa key shares the address
+	secret := decodeHex(t, "0000000000000000000000000000000000000000000000000000000000000003")
+	priv, err := UnmarshalPrivateKey(secret)
+	require.Nil(t, err)
+	ecdsaPriv, err := ecdsa.UnmarshalPrivateKey(secret, crypto.Secp256k1)
+	require.Nil(t, err)
+
+	addr, err := priv.PublicKey().Address()
+	require.Nil(t, err)
+	expected, err := ecdsaPriv.PublicKey().Address()
+	require.Nil(t, err)
+	require.Equal(t, expected, addr)
+}
@@ -0,0 +1,48 @@
+package asym
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+)
+
+type privateKeySigner struct {
+	priv crypto.PrivateKey
+}
+
+// NewSigner returns a Signer of an in-process private key.
+func NewSigner(priv crypto.PrivateKey) crypto.Signer {
+	return &privateKeySigner{priv: priv}
+}
+
+func (s *privateKeySigner) Sign(ctx context.Context, digest []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.priv.Sign(digest)
+}
+
+func (s *privateKeySigner) PublicKey() crypto.PublicKey {
+	return s.priv.PublicKey()
+}
+
+// SignWithSigner signs digest with signer and adds the key type flag in the
+// beginning, like SignWithType.
+func SignWithSigner(ctx context.Context, signer crypto.Signer, digest []byte) ([]byte, error) {
+	if signer == nil {
+		return nil, fmt.Errorf("signer is empty")
+	}
+
+	typ := signer.PublicKey().Type()
+	if _, ok := SupportKeyType()[typ]; !ok {
+		return nil, fmt.Errorf("key type %d is not supported", typ)
+	}
+
+	sig, err := signer.Sign(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{byte(typ)}, sig...), nil
+}
@@ -0,0 +1,35 @@
+package sm2
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"sync"
+)
+
+var (
+	initonce sync.Once
+	p256     *elliptic.CurveParams
+)
+
+func initP256() {
+	p256 = &elliptic.CurveParams{Name: "SM2-P-256"}
+	p256.P, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFF", 16)
+	p256.N, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFF7203DF6B21C6052B53BBF40939D54123", 16)
+	p256.B, _ = new(big.Int).SetString("28E9FA9E9D9F5E344D5A9E4BCF6509A7F39789F515AB8F92DDBCBD414D940E93", 16)
+	p256.Gx, _ = new(big.Int).SetString("32C4AE2C1F1981195F9904466A39C9948FE30BBFF2660BE1715A4589334C74C7", 16)
+	p256.Gy, _ = new(big.Int).SetString("BC3736A2F4F6779C59BDCEE36B692153D0A9877CC62A474002DF32E52139F0A0", 16)
+	p256.BitSize = 256
+}
+
+// P256 returns the sm2p256v1 curve recommended by GB/T 32918.5-2017. Its
+// parameter a equals p - 3, so the generic CurveParams arithmetic applies.
+func P256() elliptic.Curve {
+	initonce.Do(initP256)
+	return p256
+}
+
+// curveA returns the a parameter of the curve, which is p - 3.
+func curveA() *big.Int {
+	params := P256().Params()
+	return new(big.Int).Sub(params.P, big.NewInt(3))
+}
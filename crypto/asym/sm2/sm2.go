@@ -0,0 +1,324 @@
+// Package sm2 implements SM2 signatures as defined in GB/T 32918.2-2016 with
+// SM3 as digest algorithm.
+//
+// The curve arithmetic is the generic one of elliptic.CurveParams and the
+// scalar arithmetic is done with math/big, neither of which runs in constant
+// time. The timing of signing depends on the nonce and the private key, so
+// keys must not be used for signing where an attacker can measure it, for
+// instance on hosts shared with untrusted code.
+package sm2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/sm3"
+	"github.com/meshplus/bitxhub-kit/types"
+	"golang.org/x/crypto/sha3"
+)
+
+var _ crypto.PrivateKey = (*PrivateKey)(nil)
+var _ crypto.PublicKey = (*PublicKey)(nil)
+
+// DefaultUID is the signer identity used for the Z value if none is given.
+var DefaultUID = []byte("1234567812345678")
+
+var one = big.NewInt(1)
+
+// PrivateKey SM2 private key.
+type PrivateKey struct {
+	K *ecdsa.PrivateKey
+}
+
+// PublicKey SM2 public key.
+type PublicKey struct {
+	K *ecdsa.PublicKey
+}
+
+// Sig holds the r and s values of a SM2 signature and the signing public key
+type Sig struct {
+	Pub []byte   `json:"pub"`
+	R   *big.Int `json:"r"`
+	S   *big.Int `json:"s"`
+}
+
+// New generates a sm2 private key
+func New() (*PrivateKey, error) {
+	priv, err := ecdsa.GenerateKey(P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrivateKey{K: priv}, nil
+}
+
+// Bytes returns the 32 bytes big endian private scalar
+func (priv *PrivateKey) Bytes() ([]byte, error) {
+	if priv.K == nil {
+		return nil, fmt.Errorf("SM2PrivateKey.K is nil")
+	}
+
+	return paddedBytes(priv.K.D), nil
+}
+
+func (priv *PrivateKey) PublicKey() crypto.PublicKey {
+	return &PublicKey{K: &priv.K.PublicKey}
+}
+
+// Sign signs msg with the default uid. The SM3 digest of the Z value and msg
+// is computed here, so msg must not be pre-hashed with the Z value.
+func (priv *PrivateKey) Sign(msg []byte) ([]byte, error) {
+	return priv.SignWithUID(DefaultUID, msg)
+}
+
+// SignWithUID signs msg for the signer identity uid.
+func (priv *PrivateKey) SignWithUID(uid, msg []byte) ([]byte, error) {
+	if priv.K == nil {
+		return nil, fmt.Errorf("SM2PrivateKey.K is nil")
+	}
+
+	e, err := hashMsg(uid, msg, &priv.K.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	r, s, err := sign(rand.Reader, priv.K, e)
+	if err != nil {
+		return nil, err
+	}
+
+	pubBytes, err := priv.PublicKey().Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(Sig{Pub: pubBytes, R: r, S: s})
+}
+
+func (priv *PrivateKey) Type() crypto.KeyType {
+	return crypto.SM2
+}
+
+// UnmarshalPrivateKey parses a 32 bytes private scalar
+func UnmarshalPrivateKey(data []byte) (*PrivateKey, error) {
+	if len(data) != 32 {
+		return nil, fmt.Errorf("invalid sm2 private key length %d", len(data))
+	}
+
+	d := new(big.Int).SetBytes(data)
+	if d.Sign() <= 0 || d.Cmp(new(big.Int).Sub(P256().Params().N, one)) >= 0 {
+		return nil, fmt.Errorf("invalid sm2 private key")
+	}
+
+	priv := &ecdsa.PrivateKey{D: d}
+	priv.Curve = P256()
+	priv.X, priv.Y = P256().ScalarBaseMult(data)
+
+	return &PrivateKey{K: priv}, nil
+}
+
+// UnmarshalPublicKey parses a 65 bytes uncompressed public key
+func UnmarshalPublicKey(data []byte) (*PublicKey, error) {
+	x, y := elliptic.Unmarshal(P256(), data)
+	if x == nil {
+		return nil, fmt.Errorf("invalid sm2 public key")
+	}
+
+	return &PublicKey{K: &ecdsa.PublicKey{Curve: P256(), X: x, Y: y}}, nil
+}
+
+// SigToPub returns the public key embedded in a signature produced by Sign
+func SigToPub(sig []byte) (*PublicKey, error) {
+	sigStruct := &Sig{}
+	if _, err := asn1.Unmarshal(sig, sigStruct); err != nil {
+		return nil, err
+	}
+
+	return UnmarshalPublicKey(sigStruct.Pub)
+}
+
+// Bytes returns the 65 bytes uncompressed public key
+func (pub *PublicKey) Bytes() ([]byte, error) {
+	if pub.K == nil {
+		return nil, fmt.Errorf("SM2PublicKey.K is nil")
+	}
+
+	return elliptic.Marshal(P256(), pub.K.X, pub.K.Y), nil
+}
+
+// Address returns the last 20 bytes of the keccak256 hash of the public key
+func (pub *PublicKey) Address() (*types.Address, error) {
+	data, err := pub.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(data[1:])
+	ret := hash.Sum(nil)
+
+	return types.NewAddress(ret[12:]), nil
+}
+
+// Verify checks a signature of msg produced by Sign
+func (pub *PublicKey) Verify(msg []byte, sig []byte) (bool, error) {
+	return pub.VerifyWithUID(DefaultUID, msg, sig)
+}
+
+// VerifyWithUID checks a signature of msg for the signer identity uid
+func (pub *PublicKey) VerifyWithUID(uid, msg []byte, sig []byte) (bool, error) {
+	if sig == nil {
+		return false, fmt.Errorf("nil signature")
+	}
+
+	sigStruct := &Sig{}
+	if _, err := asn1.Unmarshal(sig, sigStruct); err != nil {
+		return false, err
+	}
+
+	e, err := hashMsg(uid, msg, pub.K)
+	if err != nil {
+		return false, err
+	}
+
+	if !verify(pub.K, e, sigStruct.R, sigStruct.S) {
+		return false, fmt.Errorf("invalid signature")
+	}
+
+	return true, nil
+}
+
+func (pub *PublicKey) Type() crypto.KeyType {
+	return crypto.SM2
+}
+
+// ZA computes the Z value of the signer: SM3(ENTL || ID || a || b || xG || yG || xA || yA)
+func ZA(uid []byte, pub *ecdsa.PublicKey) ([]byte, error) {
+	entl := len(uid) * 8
+	if entl > 0xffff {
+		return nil, fmt.Errorf("sm2 uid is too long")
+	}
+
+	params := P256().Params()
+	h := sm3.New()
+	h.Write([]byte{byte(entl >> 8), byte(entl)})
+	h.Write(uid)
+	h.Write(paddedBytes(curveA()))
+	h.Write(paddedBytes(params.B))
+	h.Write(paddedBytes(params.Gx))
+	h.Write(paddedBytes(params.Gy))
+	h.Write(paddedBytes(pub.X))
+	h.Write(paddedBytes(pub.Y))
+
+	return h.Sum(nil), nil
+}
+
+// hashMsg computes e = SM3(Z || msg) as integer
+func hashMsg(uid, msg []byte, pub *ecdsa.PublicKey) (*big.Int, error) {
+	za, err := ZA(uid, pub)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sm3.New()
+	h.Write(za)
+	h.Write(msg)
+
+	return new(big.Int).SetBytes(h.Sum(nil)), nil
+}
+
+func sign(rand io.Reader, priv *ecdsa.PrivateKey, e *big.Int) (*big.Int, *big.Int, error) {
+	for {
+		k, err := randScalar(rand)
+		if err != nil {
+			return nil, nil, err
+		}
+		r, s, ok := signWithK(priv, e, k)
+		if ok {
+			return r, s, nil
+		}
+	}
+}
+
+// signWithK computes the signature for the nonce k and reports false if k is
+// not usable. It is not constant time, see the package documentation.
+func signWithK(priv *ecdsa.PrivateKey, e, k *big.Int) (*big.Int, *big.Int, bool) {
+	n := P256().Params().N
+	x1, _ := P256().ScalarBaseMult(paddedBytes(k))
+
+	// r = (e + x1) mod n
+	r := new(big.Int).Add(e, x1)
+	r.Mod(r, n)
+	if r.Sign() == 0 || new(big.Int).Add(r, k).Cmp(n) == 0 {
+		return nil, nil, false
+	}
+
+	// s = (1 + d)^-1 * (k - r*d) mod n
+	dPlus1Inv := new(big.Int).Add(priv.D, one)
+	dPlus1Inv.ModInverse(dPlus1Inv, n)
+	s := new(big.Int).Mul(r, priv.D)
+	s.Sub(k, s)
+	s.Mul(s, dPlus1Inv)
+	s.Mod(s, n)
+	if s.Sign() == 0 {
+		return nil, nil, false
+	}
+
+	return r, s, true
+}
+
+func verify(pub *ecdsa.PublicKey, e, r, s *big.Int) bool {
+	if r == nil || s == nil {
+		return false
+	}
+
+	n := P256().Params().N
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(n) >= 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+
+	// t = (r + s) mod n
+	t := new(big.Int).Add(r, s)
+	t.Mod(t, n)
+	if t.Sign() == 0 {
+		return false
+	}
+
+	// (x1, y1) = s*G + t*P
+	x1, y1 := P256().ScalarBaseMult(paddedBytes(s))
+	x2, y2 := P256().ScalarMult(pub.X, pub.Y, paddedBytes(t))
+	x, _ := P256().Add(x1, y1, x2, y2)
+
+	// R = (e + x) mod n
+	x.Add(x, e)
+	x.Mod(x, n)
+	return x.Cmp(r) == 0
+}
+
+// randScalar returns a random integer in [1, n-1]
+func randScalar(rand io.Reader) (*big.Int, error) {
+	params := P256().Params()
+	b := make([]byte, params.BitSize/8+8)
+	if _, err := io.ReadFull(rand, b); err != nil {
+		return nil, err
+	}
+
+	k := new(big.Int).SetBytes(b)
+	nMinus1 := new(big.Int).Sub(params.N, one)
+	k.Mod(k, nMinus1)
+	k.Add(k, one)
+	return k, nil
+}
+
+func paddedBytes(i *big.Int) []byte {
+	ret := make([]byte, 32)
+	b := i.Bytes()
+	copy(ret[32-len(b):], b)
+	return ret
+}
@@ -0,0 +1,77 @@
+package sm2
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignWithKnownNonce(t *testing.T) {
+	// Test vector from GB/T 32918.2-2016 appendix A on the recommended curve
+	d, err := hex.DecodeString("3945208F7B2144B13F36E38AC6D39F95889393692860B51A42FB81EF4DF7C5B8")
+	require.Nil(t, err)
+	priv, err := UnmarshalPrivateKey(d)
+	require.Nil(t, err)
+
+	e, err := hashMsg(DefaultUID, []byte("message digest"), &priv.K.PublicKey)
+	require.Nil(t, err)
+	k, _ := new(big.Int).SetString("59276E27D506861A16680F3AD9C02DCCEF3CC1FA3CDBE4CE6D54B80DEAC1BC21", 16)
+	r, s, ok := signWithK(priv.K, e, k)
+	require.True(t, ok)
+	require.Equal(t, "f5a03b0648d2c4630eeac513e1bb81a15944da3827d5b74143ac7eaceee720b3", hex.EncodeToString(r.Bytes()))
+	require.Equal(t, "b1b6aa29df212fd8763182bc0d421ca1bb9038fd1f7f42d4840b69c485bbc1aa", hex.EncodeToString(s.Bytes()))
+	require.True(t, verify(&priv.K.PublicKey, e, r, s))
+}
+
+func TestSignAndVerify(t *testing.T) {
+	priv, err := New()
+	require.Nil(t, err)
+
+	msg := []byte("hyperchain")
+	sig, err := priv.Sign(msg)
+	require.Nil(t, err)
+	b, err := priv.PublicKey().Verify(msg, sig)
+	require.Nil(t, err)
+	require.True(t, b)
+
+	b, err = priv.PublicKey().Verify([]byte("hypercha1n"), sig)
+	require.NotNil(t, err)
+	require.False(t, b)
+
+	// The uid is part of the signed data
+	pub := priv.PublicKey().(*PublicKey)
+	_, err = pub.VerifyWithUID([]byte("ALICE123@YAHOO.COM"), msg, sig)
+	require.NotNil(t, err)
+	sig, err = priv.SignWithUID([]byte("ALICE123@YAHOO.COM"), msg)
+	require.Nil(t, err)
+	b, err = pub.VerifyWithUID([]byte("ALICE123@YAHOO.COM"), msg, sig)
+	require.Nil(t, err)
+	require.True(t, b)
+}
+
+func TestMarshal(t *testing.T) {
+	priv, err := New()
+	require.Nil(t, err)
+
+	data, err := priv.Bytes()
+	require.Nil(t, err)
+	restored, err := UnmarshalPrivateKey(data)
+	require.Nil(t, err)
+	require.Equal(t, priv.K.D, restored.K.D)
+
+	pubData, err := priv.PublicKey().Bytes()
+	require.Nil(t, err)
+	pub, err := UnmarshalPublicKey(pubData)
+	require.Nil(t, err)
+
+	addr1, err := restored.PublicKey().Address()
+	require.Nil(t, err)
+	addr2, err := pub.Address()
+	require.Nil(t, err)
+	require.Equal(t, addr1, addr2)
+
+	_, err = UnmarshalPrivateKey(make([]byte, 32))
+	require.NotNil(t, err)
+}
@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -19,18 +20,30 @@ const (
 	ECDSA_P521
 	SM2
 	Ed25519
+	SM4
+	AES_GCM
+	ChaCha20Poly1305
+	X25519
+	BLS12_381
+	Secp256k1Schnorr
 )
 
 var CryptoNameType = map[string]KeyType{
-	"AES":        AES,
-	"ThirdDES":   ThirdDES,
-	"RSA":        RSA,
-	"Secp256k1":  Secp256k1,
-	"ECDSA_P256": ECDSA_P256,
-	"ECDSA_P384": ECDSA_P384,
-	"ECDSA_P521": ECDSA_P521,
-	"SM2":        SM2,
-	"Ed25519":    Ed25519,
+	"AES":              AES,
+	"ThirdDES":         ThirdDES,
+	"RSA":              RSA,
+	"Secp256k1":        Secp256k1,
+	"ECDSA_P256":       ECDSA_P256,
+	"ECDSA_P384":       ECDSA_P384,
+	"ECDSA_P521":       ECDSA_P521,
+	"SM2":              SM2,
+	"Ed25519":          Ed25519,
+	"SM4":              SM4,
+	"AES_GCM":          AES_GCM,
+	"ChaCha20Poly1305": ChaCha20Poly1305,
+	"X25519":           X25519,
+	"BLS12_381":        BLS12_381,
+	"Secp256k1Schnorr": Secp256k1Schnorr,
 }
 
 type Key interface {
@@ -41,16 +54,50 @@ type Key interface {
 	Type() KeyType
 }
 
+// KeyStore is a password protected private key. Version 3 keystores follow
+// the Web3 Secret Storage Definition, extended with the key type. Keystores
+// without a version use the legacy Cipher field.
 type KeyStore struct {
-	Type   KeyType    `json:"type"`
-	Cipher *CipherKey `json:"cipher"`
+	Type    KeyType         `json:"type"`
+	Version int             `json:"version,omitempty"`
+	ID      string          `json:"id,omitempty"`
+	Address string          `json:"address,omitempty"`
+	Crypto  *KeyStoreCrypto `json:"crypto,omitempty"`
+	Cipher  *CipherKey      `json:"cipher,omitempty"`
 }
 
+// CipherKey is the encrypted private key of a legacy keystore
 type CipherKey struct {
 	Data   string `json:"data"`
 	Cipher string `json:"cipher"`
 }
 
+// KeyStoreCrypto is the encrypted private key of a version 3 keystore
+type KeyStoreCrypto struct {
+	Cipher       string       `json:"cipher"`
+	CipherText   string       `json:"ciphertext"`
+	CipherParams CipherParams `json:"cipherparams"`
+	KDF          string       `json:"kdf"`
+	KDFParams    KDFParams    `json:"kdfparams"`
+	MAC          string       `json:"mac"`
+}
+
+type CipherParams struct {
+	IV string `json:"iv"`
+}
+
+// KDFParams holds the parameters of the scrypt or argon2id key derivation
+type KDFParams struct {
+	DKLen   int    `json:"dklen"`
+	Salt    string `json:"salt"`
+	N       int    `json:"n,omitempty"`
+	R       int    `json:"r,omitempty"`
+	P       int    `json:"p,omitempty"`
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+}
+
 // PrivateKey represents a private key that can be used to
 // generate a public key and sign data
 type PrivateKey interface {
@@ -75,6 +122,29 @@ type PublicKey interface {
 	Verify(digest []byte, sig []byte) (bool, error)
 }
 
+// Signer signs with a private key which may be held out of process, for
+// instance by a signing daemon or a hardware module.
+type Signer interface {
+	// Sign signs digest like PrivateKey.Sign does.
+	Sign(ctx context.Context, digest []byte) ([]byte, error)
+
+	// PublicKey returns the public key of the signing key.
+	PublicKey() PublicKey
+}
+
+// Encrypter is implemented by public keys which support public key encryption
+type Encrypter interface {
+	// Encrypt encrypts plain text to the owner of the public key
+	Encrypt(plain []byte) ([]byte, error)
+}
+
+// Decrypter is implemented by private keys which can decrypt the output of the
+// matching Encrypter
+type Decrypter interface {
+	// Decrypt decrypts and authenticates cipher text
+	Decrypt(crypted []byte) ([]byte, error)
+}
+
 // SymmetricKey is a interface that provides symmetric encrypt and decrypt.
 type SymmetricKey interface {
 	Key
@@ -86,6 +156,18 @@ type SymmetricKey interface {
 	Decrypt(cipher []byte) (plain []byte, err error)
 }
 
+// AEADKey is a symmetric key of an authenticated encryption scheme, which can
+// also authenticate additional data that is not encrypted.
+type AEADKey interface {
+	SymmetricKey
+
+	// EncryptWithAD encrypts plain text and authenticates it together with ad.
+	EncryptWithAD(plain, ad []byte) (cipher []byte, err error)
+
+	// DecryptWithAD decrypts ciphertext and checks it together with ad.
+	DecryptWithAD(cipher, ad []byte) (plain []byte, err error)
+}
+
 func (key *KeyStore) Pretty() (string, error) {
 	ret, err := json.MarshalIndent(key, "", "	")
 	if err != nil {
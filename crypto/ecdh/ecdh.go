@@ -1,6 +1,13 @@
 package ecdh
 
-import "github.com/meshplus/bitxhub-kit/crypto"
+import (
+	"crypto/elliptic"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/sm2"
+)
 
 type KeyExchange interface {
 	// Check returns a non-nil error if the peers public key cannot used for the
@@ -12,3 +19,27 @@ type KeyExchange interface {
 	// and the peers public key.
 	ComputeSecret(privkey crypto.PrivateKey, peerPubkey []byte) (secret []byte, err error)
 }
+
+// NewKeyExchange returns the key exchange matching keys of the given type.
+// Ed25519 keys are converted to X25519, so that nodes can agree on secrets
+// with their identity keys.
+func NewKeyExchange(typ crypto.KeyType) (KeyExchange, error) {
+	switch typ {
+	case crypto.Secp256k1:
+		return NewEllipticECDH(btcec.S256())
+	case crypto.ECDSA_P256:
+		return NewEllipticECDH(elliptic.P256())
+	case crypto.ECDSA_P384:
+		return NewEllipticECDH(elliptic.P384())
+	case crypto.ECDSA_P521:
+		return NewEllipticECDH(elliptic.P521())
+	case crypto.SM2:
+		return NewEllipticECDH(sm2.P256())
+	case crypto.Ed25519:
+		return x25519ECDH{edwardsPeer: true}, nil
+	case crypto.X25519:
+		return NewX25519ECDH(), nil
+	default:
+		return nil, fmt.Errorf("key exchange is not supported for key type %d", typ)
+	}
+}
@@ -0,0 +1,169 @@
+package ecdh_test
+
+import (
+	ecdsa2 "crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/ecdsa"
+	ed25519key "github.com/meshplus/bitxhub-kit/crypto/asym/ed25519"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/sm2"
+	"github.com/meshplus/bitxhub-kit/crypto/ecdh"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestEllipticKeyExchange(t *testing.T) {
+	for _, typ := range []crypto.KeyType{crypto.Secp256k1, crypto.ECDSA_P256, crypto.ECDSA_P384, crypto.ECDSA_P521} {
+		a, err := ecdsa.New(typ)
+		require.Nil(t, err)
+		b, err := ecdsa.New(typ)
+		require.Nil(t, err)
+		testEllipticKeyExchange(t, typ, a, b, a.(*ecdsa.PrivateKey).K.PublicKey, b.(*ecdsa.PrivateKey).K.PublicKey)
+	}
+
+	a, err := sm2.New()
+	require.Nil(t, err)
+	b, err := sm2.New()
+	require.Nil(t, err)
+	testEllipticKeyExchange(t, crypto.SM2, a, b, a.K.PublicKey, b.K.PublicKey)
+}
+
+func TestEllipticSecretLength(t *testing.T) {
+	ke, err := ecdh.NewEllipticECDH(elliptic.P256())
+	require.Nil(t, err)
+	b, err := ecdsa.New(crypto.ECDSA_P256)
+	require.Nil(t, err)
+	bPub := b.(*ecdsa.PrivateKey).K.PublicKey
+
+	// About one key in 256 yields a secret with a leading zero byte, which is
+	// left out as it always was
+	for i := 0; i < 1<<13; i++ {
+		a, err := ecdsa.New(crypto.ECDSA_P256)
+		require.Nil(t, err)
+		secret, err := ke.ComputeSecret(a, elliptic.Marshal(bPub.Curve, bPub.X, bPub.Y))
+		require.Nil(t, err)
+		if len(secret) < 32 {
+			x, _ := bPub.Curve.ScalarMult(bPub.X, bPub.Y, a.(*ecdsa.PrivateKey).K.D.Bytes())
+			require.Equal(t, x.Bytes(), secret)
+			return
+		}
+	}
+	t.Fatal("no short secret found")
+}
+
+func testEllipticKeyExchange(t *testing.T, typ crypto.KeyType, a, b crypto.PrivateKey, aPub, bPub ecdsa2.PublicKey) {
+	ke, err := ecdh.NewKeyExchange(typ)
+	require.Nil(t, err)
+
+	expected, err := ke.ComputeSecret(a, elliptic.Marshal(bPub.Curve, bPub.X, bPub.Y))
+	require.Nil(t, err)
+	require.LessOrEqual(t, len(expected), (bPub.Curve.Params().BitSize+7)/8)
+
+	secret, err := ke.ComputeSecret(b, compress(aPub))
+	require.Nil(t, err)
+	require.Equal(t, expected, secret)
+
+	// Public keys in their own serialisation are accepted as well
+	aPubBytes, err := a.PublicKey().Bytes()
+	require.Nil(t, err)
+	secret, err = ke.ComputeSecret(b, aPubBytes)
+	require.Nil(t, err)
+	require.Equal(t, expected, secret)
+
+	bad := compress(aPub)
+	bad[len(bad)-1] ^= 0x01
+	if err := ke.Check(bad); err == nil {
+		secret, err = ke.ComputeSecret(b, bad)
+		require.Nil(t, err)
+		require.NotEqual(t, expected, secret)
+	}
+	require.NotNil(t, ke.Check(nil))
+}
+
+func compress(pub ecdsa2.PublicKey) []byte {
+	byteLen := (pub.Curve.Params().BitSize + 7) / 8
+	out := make([]byte, 1+byteLen)
+	out[0] = byte(2 + pub.Y.Bit(0))
+	raw := pub.X.Bytes()
+	copy(out[1+byteLen-len(raw):], raw)
+	return out
+}
+
+func TestX25519(t *testing.T) {
+	// Test vector from RFC 7748 section 6.1
+	alicePriv, _ := hex.DecodeString("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	bobPriv, _ := hex.DecodeString("5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb")
+
+	alice, err := ecdh.UnmarshalX25519PrivateKey(alicePriv)
+	require.Nil(t, err)
+	bob, err := ecdh.UnmarshalX25519PrivateKey(bobPriv)
+	require.Nil(t, err)
+	alicePub, err := alice.PublicKey().Bytes()
+	require.Nil(t, err)
+	require.Equal(t, "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a", hex.EncodeToString(alicePub))
+	bobPub, err := bob.PublicKey().Bytes()
+	require.Nil(t, err)
+	require.Equal(t, "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f", hex.EncodeToString(bobPub))
+
+	ke, err := ecdh.NewKeyExchange(crypto.X25519)
+	require.Nil(t, err)
+	secret, err := ke.ComputeSecret(alice, bobPub)
+	require.Nil(t, err)
+	require.Equal(t, "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742", hex.EncodeToString(secret))
+	secret, err = ke.ComputeSecret(bob, alicePub)
+	require.Nil(t, err)
+	require.Equal(t, "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742", hex.EncodeToString(secret))
+
+	// Low order points are rejected
+	_, err = ke.ComputeSecret(alice, make([]byte, 32))
+	require.NotNil(t, err)
+	require.NotNil(t, ke.Check(alicePub[:31]))
+
+	_, err = alice.Sign(alicePub)
+	require.NotNil(t, err)
+}
+
+func TestEd25519KeyExchange(t *testing.T) {
+	a, err := ed25519key.New()
+	require.Nil(t, err)
+	b, err := ed25519key.New()
+	require.Nil(t, err)
+	aPub, err := a.PublicKey().Bytes()
+	require.Nil(t, err)
+	bPub, err := b.PublicKey().Bytes()
+	require.Nil(t, err)
+
+	ke, err := ecdh.NewKeyExchange(crypto.Ed25519)
+	require.Nil(t, err)
+	s1, err := ke.ComputeSecret(a, bPub)
+	require.Nil(t, err)
+	s2, err := ke.ComputeSecret(b, aPub)
+	require.Nil(t, err)
+	require.Equal(t, s1, s2)
+
+	// The converted public key is the X25519 public key of the same secret
+	scalar := sha512.Sum512(a.(*ed25519key.PrivateKey).K.Seed())
+	expected, err := curve25519.X25519(scalar[:32], curve25519.Basepoint)
+	require.Nil(t, err)
+	converted, err := ecdh.Ed25519PublicKeyToX25519(aPub)
+	require.Nil(t, err)
+	require.Equal(t, expected, converted)
+
+	// An Ed25519 identity can also talk to a plain X25519 key
+	x, err := ecdh.GenerateX25519Key()
+	require.Nil(t, err)
+	xPub, err := x.PublicKey().Bytes()
+	require.Nil(t, err)
+	s1, err = ecdh.NewX25519ECDH().ComputeSecret(a, xPub)
+	require.Nil(t, err)
+	s2, err = ecdh.NewX25519ECDH().ComputeSecret(x, converted)
+	require.Nil(t, err)
+	require.Equal(t, s1, s2)
+
+	_, err = ecdh.NewKeyExchange(crypto.RSA)
+	require.NotNil(t, err)
+}
@@ -1,12 +1,14 @@
 package ecdh
 
 import (
+	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/x509"
 	"fmt"
 	"math/big"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/meshplus/bitxhub-kit/crypto"
-	"github.com/meshplus/bitxhub-kit/crypto/asym/ecdsa"
 )
 
 type ellipticECDH struct {
@@ -25,7 +27,7 @@ func (e ellipticECDH) Check(peerPubkey []byte) error {
 	if len(peerPubkey) == 0 {
 		return fmt.Errorf("empty public key byte")
 	}
-	x, y, err := getXYFromPub(peerPubkey)
+	x, y, err := e.getXYFromPub(peerPubkey)
 	if err != nil {
 		return err
 	}
@@ -36,18 +38,21 @@ func (e ellipticECDH) Check(peerPubkey []byte) error {
 	return nil
 }
 
+// ComputeSecret returns the x coordinate of the shared point without leading
+// zeros, so for a few keys it is shorter than the curve size. Existing
+// secrets depend on that, callers which need a fixed length pad it themselves.
 func (e ellipticECDH) ComputeSecret(privkey crypto.PrivateKey, peerPubkey []byte) ([]byte, error) {
 	err := e.Check(peerPubkey)
 	if err != nil {
 		return nil, err
 	}
 
-	x, y, err := getXYFromPub(peerPubkey)
+	x, y, err := e.getXYFromPub(peerPubkey)
 	if err != nil {
 		return nil, err
 	}
 
-	privBytes, err := privkey.Bytes()
+	privBytes, err := e.getScalar(privkey)
 	if err != nil {
 		return nil, err
 	}
@@ -58,12 +63,77 @@ func (e ellipticECDH) ComputeSecret(privkey crypto.PrivateKey, peerPubkey []byte
 	return secret, nil
 }
 
-func getXYFromPub(pub []byte) (*big.Int, *big.Int, error) {
-	key, err := ecdsa.UnmarshalPublicKey(pub, crypto.Secp256k1)
+func (e ellipticECDH) byteLen() int {
+	return (e.curve.Params().BitSize + 7) / 8
+}
+
+// getScalar returns the private scalar, secp256k1 keys are serialised raw
+// while the NIST curve keys are serialised as SEC1 DER.
+func (e ellipticECDH) getScalar(privkey crypto.PrivateKey) ([]byte, error) {
+	privBytes, err := privkey.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(privBytes) == e.byteLen() {
+		return privBytes, nil
+	}
+
+	key, err := x509.ParseECPrivateKey(privBytes)
+	if err != nil {
+		return nil, err
+	}
+	if key.Curve != e.curve {
+		return nil, fmt.Errorf("private key is not on curve")
+	}
+	return key.D.Bytes(), nil
+}
+
+// getXYFromPub accepts compressed and uncompressed points and, for the NIST
+// curves, PKIX DER public keys.
+func (e ellipticECDH) getXYFromPub(pub []byte) (*big.Int, *big.Int, error) {
+	if x, y := elliptic.Unmarshal(e.curve, pub); x != nil {
+		return x, y, nil
+	}
+	if len(pub) == 1+e.byteLen() && (pub[0] == 2 || pub[0] == 3) {
+		return e.decompress(pub)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(pub)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, fmt.Errorf("invalid public key")
+	}
+	pubKey, ok := key.(*ecdsa.PublicKey)
+	if !ok || pubKey.Curve != e.curve {
+		return nil, nil, fmt.Errorf("peer's public key is not on curve")
 	}
+	return pubKey.X, pubKey.Y, nil
+}
 
-	pubKey := key.(*ecdsa.PublicKey)
-	return pubKey.K.X, pubKey.K.Y, nil
+// decompress solves y^2 = x^3 + ax + b for a compressed point, a is 0 for
+// secp256k1 and -3 for the other supported curves.
+func (e ellipticECDH) decompress(pub []byte) (*big.Int, *big.Int, error) {
+	params := e.curve.Params()
+	p := params.P
+	x := new(big.Int).SetBytes(pub[1:])
+	if x.Cmp(p) >= 0 {
+		return nil, nil, fmt.Errorf("invalid public key")
+	}
+
+	y2 := new(big.Int).Exp(x, big.NewInt(3), p)
+	if p.Cmp(btcec.S256().P) != 0 {
+		threeX := new(big.Int).Lsh(x, 1)
+		threeX.Add(threeX, x)
+		y2.Sub(y2, threeX)
+	}
+	y2.Add(y2, params.B)
+	y2.Mod(y2, p)
+
+	y := new(big.Int).ModSqrt(y2, p)
+	if y == nil {
+		return nil, nil, fmt.Errorf("invalid public key")
+	}
+	if y.Bit(0) != uint(pub[0]&1) {
+		y.Sub(p, y)
+	}
+	return x, y, nil
 }
@@ -0,0 +1,193 @@
+package ecdh
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	ed25519key "github.com/meshplus/bitxhub-kit/crypto/asym/ed25519"
+	"github.com/meshplus/bitxhub-kit/types"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/sha3"
+)
+
+var _ crypto.PrivateKey = (*X25519PrivateKey)(nil)
+var _ crypto.PublicKey = (*X25519PublicKey)(nil)
+
+// curve25519P is 2^255 - 19
+var curve25519P = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+type x25519ECDH struct {
+	// edwardsPeer interprets peer public keys as Ed25519 public keys
+	edwardsPeer bool
+}
+
+// NewX25519ECDH returns an X25519 key exchange. The private key may be an
+// X25519 or an Ed25519 private key, peer public keys are 32 byte X25519 keys.
+func NewX25519ECDH() KeyExchange {
+	return x25519ECDH{}
+}
+
+func (e x25519ECDH) Check(peerPubkey []byte) error {
+	_, err := e.peerKey(peerPubkey)
+	return err
+}
+
+func (e x25519ECDH) ComputeSecret(privkey crypto.PrivateKey, peerPubkey []byte) ([]byte, error) {
+	peer, err := e.peerKey(peerPubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	scalar, err := x25519Scalar(privkey)
+	if err != nil {
+		return nil, err
+	}
+
+	// X25519 rejects low order points, which would yield an all zero secret
+	return curve25519.X25519(scalar, peer)
+}
+
+func (e x25519ECDH) peerKey(peerPubkey []byte) ([]byte, error) {
+	if len(peerPubkey) != curve25519.PointSize {
+		return nil, fmt.Errorf("invalid x25519 public key length %d", len(peerPubkey))
+	}
+	if e.edwardsPeer {
+		return Ed25519PublicKeyToX25519(peerPubkey)
+	}
+	return peerPubkey, nil
+}
+
+// x25519Scalar returns the X25519 scalar of an X25519 or Ed25519 private key.
+func x25519Scalar(privkey crypto.PrivateKey) ([]byte, error) {
+	switch key := privkey.(type) {
+	case *X25519PrivateKey:
+		return key.k[:], nil
+	case *ed25519key.PrivateKey:
+		h := sha512.Sum512(key.K.Seed())
+		return h[:curve25519.ScalarSize], nil
+	default:
+		return nil, fmt.Errorf("x25519 needs an X25519 or Ed25519 private key")
+	}
+}
+
+// Ed25519PublicKeyToX25519 maps an Ed25519 public key to the X25519 public
+// key of the same secret, u = (1 + y) / (1 - y).
+func Ed25519PublicKeyToX25519(pub []byte) ([]byte, error) {
+	if len(pub) != 32 {
+		return nil, fmt.Errorf("invalid ed25519 public key length %d", len(pub))
+	}
+
+	// The point is encoded as little endian y, with the sign of x in the top bit
+	le := make([]byte, 32)
+	copy(le, pub)
+	le[31] &= 0x7f
+	y := new(big.Int).SetBytes(reverse(le))
+	if y.Cmp(curve25519P) >= 0 {
+		return nil, fmt.Errorf("invalid ed25519 public key")
+	}
+
+	den := new(big.Int).Sub(big.NewInt(1), y)
+	den.Mod(den, curve25519P)
+	if den.Sign() == 0 {
+		return nil, fmt.Errorf("invalid ed25519 public key")
+	}
+	u := new(big.Int).Add(big.NewInt(1), y)
+	u.Mul(u, den.ModInverse(den, curve25519P))
+	u.Mod(u, curve25519P)
+
+	out := make([]byte, 32)
+	raw := u.Bytes()
+	copy(out[32-len(raw):], raw)
+	return reverse(out), nil
+}
+
+func reverse(b []byte) []byte {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
+
+// X25519PrivateKey is a key for X25519 key exchange only, it can't sign.
+type X25519PrivateKey struct {
+	k [curve25519.ScalarSize]byte
+}
+
+// X25519PublicKey is the public half of an X25519PrivateKey.
+type X25519PublicKey struct {
+	k [curve25519.PointSize]byte
+}
+
+// GenerateX25519Key generates a X25519 private key
+func GenerateX25519Key() (*X25519PrivateKey, error) {
+	priv := &X25519PrivateKey{}
+	if _, err := rand.Read(priv.k[:]); err != nil {
+		return nil, err
+	}
+	return priv, nil
+}
+
+// UnmarshalX25519PrivateKey parses a 32 byte X25519 private key
+func UnmarshalX25519PrivateKey(data []byte) (*X25519PrivateKey, error) {
+	if len(data) != curve25519.ScalarSize {
+		return nil, fmt.Errorf("invalid x25519 private key length %d", len(data))
+	}
+	priv := &X25519PrivateKey{}
+	copy(priv.k[:], data)
+	return priv, nil
+}
+
+// UnmarshalX25519PublicKey parses a 32 byte X25519 public key
+func UnmarshalX25519PublicKey(data []byte) (*X25519PublicKey, error) {
+	if len(data) != curve25519.PointSize {
+		return nil, fmt.Errorf("invalid x25519 public key length %d", len(data))
+	}
+	pub := &X25519PublicKey{}
+	copy(pub.k[:], data)
+	return pub, nil
+}
+
+func (priv *X25519PrivateKey) Bytes() ([]byte, error) {
+	r := make([]byte, len(priv.k))
+	copy(r, priv.k[:])
+	return r, nil
+}
+
+func (priv *X25519PrivateKey) PublicKey() crypto.PublicKey {
+	pub := &X25519PublicKey{}
+	point, _ := curve25519.X25519(priv.k[:], curve25519.Basepoint)
+	copy(pub.k[:], point)
+	return pub
+}
+
+func (priv *X25519PrivateKey) Sign(digest []byte) ([]byte, error) {
+	return nil, fmt.Errorf("x25519 keys can't sign")
+}
+
+func (priv *X25519PrivateKey) Type() crypto.KeyType {
+	return crypto.X25519
+}
+
+func (pub *X25519PublicKey) Bytes() ([]byte, error) {
+	r := make([]byte, len(pub.k))
+	copy(r, pub.k[:])
+	return r, nil
+}
+
+// Address returns the last 20 bytes of keccak256(pub)
+func (pub *X25519PublicKey) Address() (*types.Address, error) {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(pub.k[:])
+	return types.NewAddress(hash.Sum(nil)[12:]), nil
+}
+
+func (pub *X25519PublicKey) Verify(digest []byte, sig []byte) (bool, error) {
+	return false, fmt.Errorf("x25519 keys can't verify signatures")
+}
+
+func (pub *X25519PublicKey) Type() crypto.KeyType {
+	return crypto.X25519
+}
@@ -0,0 +1,301 @@
+// Package hd implements hierarchical deterministic secp256k1 keys as
+// described in BIP32, BIP39 and BIP44.
+package hd
+
+import (
+	"bytes"
+	ecdsa2 "crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/ecdsa"
+	"github.com/mr-tron/base58"
+	"golang.org/x/crypto/ripemd160"
+)
+
+var (
+	// PrivateVersion and PublicVersion are the mainnet version bytes of
+	// serialised extended keys (xprv and xpub)
+	PrivateVersion = []byte{0x04, 0x88, 0xad, 0xe4}
+	PublicVersion  = []byte{0x04, 0x88, 0xb2, 0x1e}
+
+	// ErrInvalidChild is returned for the rare indexes which do not produce a
+	// valid key, the caller should continue with the next index.
+	ErrInvalidChild = errors.New("invalid child key, try the next index")
+
+	masterKey = []byte("Bitcoin seed")
+)
+
+const serializedKeyLen = 78
+
+// ExtendedKey is a BIP32 private or public extended key.
+type ExtendedKey struct {
+	version   []byte
+	depth     uint8
+	parentFP  []byte
+	childNum  uint32
+	chainCode []byte
+	key       []byte // 32 byte private key or 33 byte compressed public key
+	isPrivate bool
+}
+
+// NewMaster derives the master extended key from a seed.
+func NewMaster(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("invalid seed length %d", len(seed))
+	}
+
+	mac := hmac.New(sha512.New, masterKey)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(ecdsa.S256().Params().N) >= 0 {
+		return nil, fmt.Errorf("unusable seed")
+	}
+
+	return &ExtendedKey{
+		version:   PrivateVersion,
+		parentFP:  []byte{0, 0, 0, 0},
+		chainCode: sum[32:],
+		key:       sum[:32],
+		isPrivate: true,
+	}, nil
+}
+
+// NewMasterFromMnemonic derives the master extended key from a BIP39 mnemonic.
+func NewMasterFromMnemonic(mnemonic, passphrase string) (*ExtendedKey, error) {
+	seed, err := NewSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewMaster(seed)
+}
+
+// IsPrivate reports whether the extended key holds a private key.
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.isPrivate
+}
+
+// Depth returns the number of derivations from the master key.
+func (k *ExtendedKey) Depth() uint8 {
+	return k.depth
+}
+
+// ChildNumber returns the index this key was derived with.
+func (k *ExtendedKey) ChildNumber() uint32 {
+	return k.childNum
+}
+
+// Child derives the child key with the given index. Hardened indexes
+// (>= HardenedKeyStart) can only be derived from private keys.
+func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+	if k.depth == 255 {
+		return nil, fmt.Errorf("cannot derive beyond depth 255")
+	}
+
+	pub := k.pubKeyBytes()
+	data := make([]byte, 0, 37)
+	if i >= HardenedKeyStart {
+		if !k.isPrivate {
+			return nil, fmt.Errorf("cannot derive a hardened key from a public key")
+		}
+		data = append(data, 0x00)
+		data = append(data, k.key...)
+	} else {
+		data = append(data, pub...)
+	}
+	var index [4]byte
+	binary.BigEndian.PutUint32(index[:], i)
+	data = append(data, index[:]...)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	curve := ecdsa.S256()
+	n := curve.Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, ErrInvalidChild
+	}
+
+	var childKey []byte
+	if k.isPrivate {
+		child := new(big.Int).Add(il, new(big.Int).SetBytes(k.key))
+		child.Mod(child, n)
+		if child.Sign() == 0 {
+			return nil, ErrInvalidChild
+		}
+		childKey = ecdsa.PaddedBigBytes(child, 32)
+	} else {
+		parent, err := ecdsa.DecompressPubkey(k.key)
+		if err != nil {
+			return nil, err
+		}
+		x, y := curve.ScalarBaseMult(sum[:32])
+		x, y = curve.Add(x, y, parent.X, parent.Y)
+		if x.Sign() == 0 && y.Sign() == 0 {
+			return nil, ErrInvalidChild
+		}
+		childKey = ecdsa.CompressPubkey(&ecdsa2.PublicKey{Curve: curve, X: x, Y: y})
+	}
+
+	return &ExtendedKey{
+		version:   k.version,
+		depth:     k.depth + 1,
+		parentFP:  hash160(pub)[:4],
+		childNum:  i,
+		chainCode: sum[32:],
+		key:       childKey,
+		isPrivate: k.isPrivate,
+	}, nil
+}
+
+// Derive derives the key at the given path relative to this key.
+func (k *ExtendedKey) Derive(path DerivationPath) (*ExtendedKey, error) {
+	key := k
+	for _, i := range path {
+		child, err := key.Child(i)
+		if err != nil {
+			return nil, fmt.Errorf("derive %s: %w", path, err)
+		}
+		key = child
+	}
+	return key, nil
+}
+
+// Neuter returns the public extended key of a private extended key.
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	if !k.isPrivate {
+		return k
+	}
+
+	return &ExtendedKey{
+		version:   PublicVersion,
+		depth:     k.depth,
+		parentFP:  k.parentFP,
+		childNum:  k.childNum,
+		chainCode: k.chainCode,
+		key:       k.pubKeyBytes(),
+	}
+}
+
+// PrivateKey returns the secp256k1 private key of a private extended key.
+func (k *ExtendedKey) PrivateKey() (*ecdsa.PrivateKey, error) {
+	if !k.isPrivate {
+		return nil, fmt.Errorf("not a private extended key")
+	}
+	return ecdsa.UnmarshalPrivateKey(k.key, crypto.Secp256k1)
+}
+
+// PublicKey returns the secp256k1 public key of the extended key.
+func (k *ExtendedKey) PublicKey() (*ecdsa.PublicKey, error) {
+	pub, err := ecdsa.DecompressPubkey(k.pubKeyBytes())
+	if err != nil {
+		return nil, err
+	}
+	return ecdsa.NewPublicKey(*pub)
+}
+
+// String returns the base58check serialisation (xprv or xpub) of the key.
+func (k *ExtendedKey) String() string {
+	buf := make([]byte, 0, serializedKeyLen+4)
+	buf = append(buf, k.version...)
+	buf = append(buf, k.depth)
+	buf = append(buf, k.parentFP...)
+	var index [4]byte
+	binary.BigEndian.PutUint32(index[:], k.childNum)
+	buf = append(buf, index[:]...)
+	buf = append(buf, k.chainCode...)
+	if k.isPrivate {
+		buf = append(buf, 0x00)
+	}
+	buf = append(buf, k.key...)
+	buf = append(buf, doubleSha256(buf)[:4]...)
+	return base58.Encode(buf)
+}
+
+// ParseExtendedKey parses a base58check serialised extended key.
+func ParseExtendedKey(s string) (*ExtendedKey, error) {
+	buf, err := base58.Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) != serializedKeyLen+4 {
+		return nil, fmt.Errorf("invalid extended key length %d", len(buf))
+	}
+	payload, checksum := buf[:serializedKeyLen], buf[serializedKeyLen:]
+	if !bytes.Equal(doubleSha256(payload)[:4], checksum) {
+		return nil, fmt.Errorf("invalid extended key checksum")
+	}
+
+	key := &ExtendedKey{
+		version:   payload[:4],
+		depth:     payload[4],
+		parentFP:  payload[5:9],
+		childNum:  binary.BigEndian.Uint32(payload[9:13]),
+		chainCode: payload[13:45],
+		key:       payload[45:],
+	}
+	switch {
+	case bytes.Equal(key.version, PrivateVersion):
+		if key.key[0] != 0x00 {
+			return nil, fmt.Errorf("invalid private extended key")
+		}
+		key.key = key.key[1:]
+		k := new(big.Int).SetBytes(key.key)
+		if k.Sign() == 0 || k.Cmp(ecdsa.S256().Params().N) >= 0 {
+			return nil, fmt.Errorf("invalid private extended key")
+		}
+		key.isPrivate = true
+	case bytes.Equal(key.version, PublicVersion):
+		if _, err := ecdsa.DecompressPubkey(key.key); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown extended key version %x", key.version)
+	}
+	return key, nil
+}
+
+// DeriveFromMnemonic returns the private key at the given path of the wallet
+// described by the mnemonic.
+func DeriveFromMnemonic(mnemonic, passphrase string, path DerivationPath) (*ecdsa.PrivateKey, error) {
+	master, err := NewMasterFromMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	key, err := master.Derive(path)
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey()
+}
+
+func (k *ExtendedKey) pubKeyBytes() []byte {
+	if !k.isPrivate {
+		return k.key
+	}
+	x, y := ecdsa.S256().ScalarBaseMult(k.key)
+	return ecdsa.CompressPubkey(&ecdsa2.PublicKey{Curve: ecdsa.S256(), X: x, Y: y})
+}
+
+func hash160(data []byte) []byte {
+	sha := sha256.Sum256(data)
+	hash := ripemd160.New()
+	hash.Write(sha[:])
+	return hash.Sum(nil)
+}
+
+func doubleSha256(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
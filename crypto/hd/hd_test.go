@@ -0,0 +1,189 @@
+package hd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordlist(t *testing.T) {
+	require.Equal(t, 2048, len(englishWords))
+	hash := sha256.Sum256([]byte(strings.Join(englishWords, "\n") + "\n"))
+	require.Equal(t, "2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda", hex.EncodeToString(hash[:]))
+}
+
+func TestMnemonic(t *testing.T) {
+	// Test vectors from the BIP39 reference implementation, passphrase TREZOR
+	cases := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			entropy:  "00000000000000000000000000000000",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+			seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			entropy:  "ffffffffffffffffffffffffffffffff",
+			mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+			seed:     "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+		},
+	}
+	for _, c := range cases {
+		entropy, err := hex.DecodeString(c.entropy)
+		require.Nil(t, err)
+		mnemonic, err := NewMnemonic(entropy)
+		require.Nil(t, err)
+		require.Equal(t, c.mnemonic, mnemonic)
+
+		decoded, err := EntropyFromMnemonic(mnemonic)
+		require.Nil(t, err)
+		require.Equal(t, entropy, decoded)
+
+		seed, err := NewSeed(mnemonic, "TREZOR")
+		require.Nil(t, err)
+		require.Equal(t, c.seed, hex.EncodeToString(seed))
+	}
+
+	for _, bits := range []int{128, 160, 192, 224, 256} {
+		mnemonic, err := GenerateMnemonic(bits)
+		require.Nil(t, err)
+		require.Equal(t, bits/32*3, len(strings.Fields(mnemonic)))
+		require.True(t, ValidateMnemonic(mnemonic))
+	}
+
+	_, err := GenerateMnemonic(100)
+	require.NotNil(t, err)
+	require.False(t, ValidateMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon"))
+	require.False(t, ValidateMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon bitxhub"))
+	_, err = NewSeed("zoo zoo zoo", "")
+	require.NotNil(t, err)
+}
+
+func TestExtendedKey(t *testing.T) {
+	// Test vector 1 from BIP32
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	require.Nil(t, err)
+	master, err := NewMaster(seed)
+	require.Nil(t, err)
+
+	cases := []struct {
+		path string
+		xpub string
+		xprv string
+	}{
+		{
+			path: "m",
+			xpub: "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+			xprv: "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+		},
+		{
+			path: "m/0'",
+			xpub: "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+			xprv: "xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7",
+		},
+		{
+			path: "m/0'/1",
+			xpub: "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+			xprv: "xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs",
+		},
+		{
+			path: "m/0'/1/2'/2/1000000000",
+			xpub: "xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+			xprv: "xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76",
+		},
+	}
+	for _, c := range cases {
+		path, err := ParsePath(c.path)
+		require.Nil(t, err)
+		require.Equal(t, c.path, path.String())
+
+		key, err := master.Derive(path)
+		require.Nil(t, err)
+		require.Equal(t, c.xprv, key.String())
+		require.Equal(t, c.xpub, key.Neuter().String())
+
+		parsed, err := ParseExtendedKey(c.xprv)
+		require.Nil(t, err)
+		require.True(t, parsed.IsPrivate())
+		require.Equal(t, c.xprv, parsed.String())
+		parsed, err = ParseExtendedKey(c.xpub)
+		require.Nil(t, err)
+		require.False(t, parsed.IsPrivate())
+		require.Equal(t, c.xpub, parsed.String())
+	}
+
+	// Public derivation matches private derivation for normal indexes
+	hardened, err := master.Child(HardenedKeyStart)
+	require.Nil(t, err)
+	priv, err := hardened.Child(1)
+	require.Nil(t, err)
+	pub, err := hardened.Neuter().Child(1)
+	require.Nil(t, err)
+	require.Equal(t, priv.Neuter().String(), pub.String())
+	_, err = hardened.Neuter().Child(HardenedKeyStart)
+	require.NotNil(t, err)
+	_, err = pub.PrivateKey()
+	require.NotNil(t, err)
+
+	_, err = ParseExtendedKey(corruptLast(cases[0].xprv))
+	require.NotNil(t, err)
+}
+
+func corruptLast(s string) string {
+	if s[len(s)-1] == 'a' {
+		return s[:len(s)-1] + "b"
+	}
+	return s[:len(s)-1] + "a"
+}
+
+func TestDeriveFromMnemonic(t *testing.T) {
+	mnemonic, err := GenerateMnemonic(256)
+	require.Nil(t, err)
+
+	path := BIP44Path(DefaultCoinType, 0, 0, 0)
+	require.Equal(t, "m/44'/60'/0'/0/0", path.String())
+
+	priv, err := DeriveFromMnemonic(mnemonic, "", path)
+	require.Nil(t, err)
+	require.Equal(t, crypto.KeyType(crypto.Secp256k1), priv.Type())
+
+	// The derived key signs like any other secp256k1 key
+	digest := sha256.Sum256([]byte("bitxhub"))
+	sig, err := priv.Sign(digest[:])
+	require.Nil(t, err)
+	addr, err := priv.PublicKey().Address()
+	require.Nil(t, err)
+	ok, err := asym.Verify(crypto.Secp256k1, sig, digest[:], *addr)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	// Derivation is deterministic and depends on the index
+	again, err := DeriveFromMnemonic(mnemonic, "", path)
+	require.Nil(t, err)
+	againAddr, err := again.PublicKey().Address()
+	require.Nil(t, err)
+	require.Equal(t, addr.String(), againAddr.String())
+
+	next, err := DeriveFromMnemonic(mnemonic, "", BIP44Path(DefaultCoinType, 0, 0, 1))
+	require.Nil(t, err)
+	nextAddr, err := next.PublicKey().Address()
+	require.Nil(t, err)
+	require.NotEqual(t, addr.String(), nextAddr.String())
+
+	_, err = ParsePath("44'/60'")
+	require.NotNil(t, err)
+	_, err = ParsePath("m/2147483648")
+	require.NotNil(t, err)
+}
@@ -0,0 +1,127 @@
+package hd
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	seedIterations = 2048
+	// SeedLength is the length of the seed derived from a mnemonic
+	SeedLength = 64
+)
+
+// NewEntropy returns random entropy for a mnemonic, bitSize must be a
+// multiple of 32 in [128, 256].
+func NewEntropy(bitSize int) ([]byte, error) {
+	if err := checkEntropySize(bitSize); err != nil {
+		return nil, err
+	}
+
+	entropy := make([]byte, bitSize/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+	return entropy, nil
+}
+
+// NewMnemonic encodes the entropy as a BIP39 mnemonic.
+func NewMnemonic(entropy []byte) (string, error) {
+	bitSize := len(entropy) * 8
+	if err := checkEntropySize(bitSize); err != nil {
+		return "", err
+	}
+	checksumSize := bitSize / 32
+
+	// Append the first bits of sha256(entropy) as checksum
+	hash := sha256.Sum256(entropy)
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(checksumSize))
+	data.Or(data, big.NewInt(int64(hash[0]>>(8-checksumSize))))
+
+	count := (bitSize + checksumSize) / 11
+	words := make([]string, count)
+	mask := big.NewInt(2047)
+	index := new(big.Int)
+	for i := count - 1; i >= 0; i-- {
+		index.And(data, mask)
+		words[i] = englishWords[index.Int64()]
+		data.Rsh(data, 11)
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+// GenerateMnemonic returns a mnemonic encoding bitSize bits of fresh entropy.
+func GenerateMnemonic(bitSize int) (string, error) {
+	entropy, err := NewEntropy(bitSize)
+	if err != nil {
+		return "", err
+	}
+	return NewMnemonic(entropy)
+}
+
+// EntropyFromMnemonic decodes the mnemonic and verifies its checksum.
+func EntropyFromMnemonic(mnemonic string) ([]byte, error) {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	if len(words)%3 != 0 || len(words) < 12 || len(words) > 24 {
+		return nil, fmt.Errorf("invalid mnemonic length %d", len(words))
+	}
+
+	data := new(big.Int)
+	for _, word := range words {
+		index, ok := englishIndex[word]
+		if !ok {
+			return nil, fmt.Errorf("invalid mnemonic word %q", word)
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+
+	checksumSize := len(words) * 11 / 33
+	checksum := new(big.Int).And(data, big.NewInt(1<<uint(checksumSize)-1))
+	data.Rsh(data, uint(checksumSize))
+
+	entropy := make([]byte, checksumSize*4)
+	raw := data.Bytes()
+	copy(entropy[len(entropy)-len(raw):], raw)
+	hash := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(hash[0]>>(8-checksumSize)) {
+		return nil, fmt.Errorf("invalid mnemonic checksum")
+	}
+
+	return entropy, nil
+}
+
+// ValidateMnemonic reports whether the mnemonic consists of known words and
+// has a valid checksum.
+func ValidateMnemonic(mnemonic string) bool {
+	_, err := EntropyFromMnemonic(mnemonic)
+	return err == nil
+}
+
+// NewSeed validates the mnemonic and derives the BIP39 seed from it, the
+// passphrase may be empty.
+func NewSeed(mnemonic, passphrase string) ([]byte, error) {
+	if _, err := EntropyFromMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+
+	password := norm.NFKD.String(strings.Join(strings.Fields(mnemonic), " "))
+	salt := norm.NFKD.String("mnemonic" + passphrase)
+	return pbkdf2.Key([]byte(password), []byte(salt), seedIterations, SeedLength, sha512.New), nil
+}
+
+func checkEntropySize(bitSize int) error {
+	if bitSize%32 != 0 || bitSize < 128 || bitSize > 256 {
+		return fmt.Errorf("invalid entropy size %d", bitSize)
+	}
+	return nil
+}
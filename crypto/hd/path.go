@@ -0,0 +1,73 @@
+package hd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HardenedKeyStart is the index of the first hardened child key
+	HardenedKeyStart uint32 = 0x80000000
+
+	// BIP44Purpose is the purpose field of BIP44 paths
+	BIP44Purpose = 44
+	// DefaultCoinType is the SLIP-44 coin type of Ethereum compatible addresses
+	DefaultCoinType = 60
+)
+
+// DerivationPath is a sequence of child indexes starting at the master key
+type DerivationPath []uint32
+
+// ParsePath parses a path such as m/44'/60'/0'/0/0, hardened indexes are
+// marked with ' or h.
+func ParsePath(path string) (DerivationPath, error) {
+	elems := strings.Split(strings.TrimSpace(path), "/")
+	if len(elems) == 0 || elems[0] != "m" {
+		return nil, fmt.Errorf("derivation path %q must start with m", path)
+	}
+
+	result := make(DerivationPath, 0, len(elems)-1)
+	for _, elem := range elems[1:] {
+		hardened := false
+		if strings.HasSuffix(elem, "'") || strings.HasSuffix(elem, "h") {
+			hardened = true
+			elem = elem[:len(elem)-1]
+		}
+		index, err := strconv.ParseUint(elem, 10, 32)
+		if err != nil || uint32(index) >= HardenedKeyStart {
+			return nil, fmt.Errorf("invalid index %q in derivation path %q", elem, path)
+		}
+		if hardened {
+			index += uint64(HardenedKeyStart)
+		}
+		result = append(result, uint32(index))
+	}
+	return result, nil
+}
+
+// BIP44Path returns the path m/44'/coin'/account'/change/index
+func BIP44Path(coinType, account, change, index uint32) DerivationPath {
+	return DerivationPath{
+		HardenedKeyStart + BIP44Purpose,
+		HardenedKeyStart + coinType,
+		HardenedKeyStart + account,
+		change,
+		index,
+	}
+}
+
+func (p DerivationPath) String() string {
+	var builder strings.Builder
+	builder.WriteString("m")
+	for _, index := range p {
+		builder.WriteString("/")
+		if index >= HardenedKeyStart {
+			builder.WriteString(strconv.FormatUint(uint64(index-HardenedKeyStart), 10))
+			builder.WriteString("'")
+		} else {
+			builder.WriteString(strconv.FormatUint(uint64(index), 10))
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,134 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	ggio "github.com/gogo/protobuf/io"
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym"
+	signer_pb "github.com/meshplus/bitxhub-kit/crypto/signer/pb"
+)
+
+var _ crypto.Signer = (*Client)(nil)
+
+// Client is a crypto.Signer backed by a signing daemon. Requests are sent
+// one at a time over a single connection.
+type Client struct {
+	lock   sync.Mutex
+	conn   net.Conn
+	reader ggio.ReadCloser
+	writer ggio.WriteCloser
+	broken error
+	pub    crypto.PublicKey
+}
+
+// Dial connects to the signing daemon listening on the Unix socket at path
+// and fetches its public key.
+func Dial(ctx context.Context, path string) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		conn:   conn,
+		reader: ggio.NewDelimitedReader(conn, maxMessageSize),
+		writer: ggio.NewDelimitedWriter(conn),
+	}
+	resp, err := c.call(ctx, &signer_pb.Request{Type: signer_pb.Request_PUBLIC_KEY})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	pub, err := asym.UnmarshalPublicKey(resp.PublicKey, crypto.KeyType(resp.KeyType))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unmarshal signer public key: %w", err)
+	}
+	c.pub = pub
+
+	return c, nil
+}
+
+// Sign asks the daemon to sign digest and checks the signature against the
+// public key reported when connecting.
+func (c *Client) Sign(ctx context.Context, digest []byte) ([]byte, error) {
+	resp, err := c.call(ctx, &signer_pb.Request{Type: signer_pb.Request_SIGN, Digest: digest})
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := c.pub.Verify(digest, resp.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("verify signer signature: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("verify signer signature: invalid signature")
+	}
+	return resp.Signature, nil
+}
+
+// PublicKey returns the public key the daemon reported when connecting.
+func (c *Client) PublicKey() crypto.PublicKey {
+	return c.pub
+}
+
+// Close closes the connection to the daemon.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) call(ctx context.Context, req *signer_pb.Request) (*signer_pb.Response, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// A failed exchange leaves the stream out of sync
+	if c.broken != nil {
+		return nil, fmt.Errorf("signer connection is broken: %w", c.broken)
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	var (
+		done    = make(chan struct{})
+		stopped = make(chan struct{})
+	)
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			c.conn.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+	// The watcher must be gone before the next call sets its own deadline
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+
+	resp := &signer_pb.Response{}
+	err := c.writer.WriteMsg(req)
+	if err == nil {
+		err = c.reader.ReadMsg(resp)
+	}
+	if err != nil {
+		c.broken = err
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	if resp.Error != "" {
+		return nil, fmt.Errorf("signer: %s", resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,199 @@
+// Package signer implements a signing daemon, which holds a private key in a
+// separate process, and a crypto.Signer client of it. Both talk length
+// delimited protobuf messages over a Unix socket.
+package signer
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	ggio "github.com/gogo/protobuf/io"
+	"github.com/meshplus/bitxhub-kit/crypto"
+	signer_pb "github.com/meshplus/bitxhub-kit/crypto/signer/pb"
+)
+
+// maxMessageSize bounds the size of requests and responses
+const maxMessageSize = 1 << 20
+
+// Server answers public key and sign requests with its private key.
+type Server struct {
+	priv crypto.PrivateKey
+
+	lock     sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer creates a server for the private key.
+func NewServer(priv crypto.PrivateKey) *Server {
+	return &Server{
+		priv:  priv,
+		conns: make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe serves on a Unix socket at path, which only the owner of the
+// process may access. The socket is bound in a private directory and only
+// linked to path once its permissions are set, so that no one else can
+// connect in between.
+func (s *Server) ListenAndServe(path string) error {
+	listener, err := listenPrivate(path)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(listener)
+}
+
+func listenPrivate(path string) (net.Listener, error) {
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".signer-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "signer.sock")
+	listener, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	// Unlike a rename, a link fails if path exists, as binding would
+	if err := os.Link(tmp, path); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return &unlinkListener{Listener: listener, path: path}, nil
+}
+
+// unlinkListener removes the socket file at path when it is closed.
+type unlinkListener struct {
+	net.Listener
+	path string
+}
+
+func (l *unlinkListener) Close() error {
+	err := l.Listener.Close()
+	os.Remove(l.path)
+	return err
+}
+
+// Serve accepts connections until the server is closed.
+func (s *Server) Serve(listener net.Listener) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		listener.Close()
+		return nil
+	}
+	s.listener = listener
+	s.lock.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.lock.Lock()
+			closed := s.closed
+			s.lock.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.lock.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+// Close stops the server and closes all connections.
+func (s *Server) Close() error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil
+	}
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.lock.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+		conn.Close()
+		s.wg.Done()
+	}()
+
+	reader := ggio.NewDelimitedReader(conn, maxMessageSize)
+	writer := ggio.NewDelimitedWriter(conn)
+	for {
+		req := &signer_pb.Request{}
+		if err := reader.ReadMsg(req); err != nil {
+			if err != io.EOF {
+				writer.WriteMsg(&signer_pb.Response{Error: err.Error()})
+			}
+			return
+		}
+
+		if err := writer.WriteMsg(s.answer(req)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) answer(req *signer_pb.Request) *signer_pb.Response {
+	resp := &signer_pb.Response{KeyType: uint32(s.priv.Type())}
+
+	switch req.Type {
+	case signer_pb.Request_PUBLIC_KEY:
+		pub, err := s.priv.PublicKey().Bytes()
+		if err != nil {
+			resp.Error = err.Error()
+			break
+		}
+		resp.PublicKey = pub
+	case signer_pb.Request_SIGN:
+		sig, err := s.priv.Sign(req.Digest)
+		if err != nil {
+			resp.Error = err.Error()
+			break
+		}
+		resp.Signature = sig
+	default:
+		resp.Error = "unknown request type " + req.Type.String()
+	}
+
+	return resp
+}
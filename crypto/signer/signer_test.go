@@ -0,0 +1,128 @@
+package signer
+
+import (
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym"
+	"github.com/stretchr/testify/require"
+)
+
+func startServer(t *testing.T, priv crypto.PrivateKey) (*Server, string) {
+	dir, err := ioutil.TempDir("", "signer")
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "signer.sock")
+	server := NewServer(priv)
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.ListenAndServe(path) }()
+	t.Cleanup(func() {
+		require.Nil(t, server.Close())
+		require.Nil(t, <-errCh)
+	})
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	return server, path
+}
+
+func TestSigner(t *testing.T) {
+	digest := sha256.Sum256([]byte("bitxhub"))
+
+	for _, opt := range []crypto.KeyType{crypto.Secp256k1, crypto.ECDSA_P256, crypto.Ed25519, crypto.SM2} {
+		priv, err := asym.GenerateKeyPair(opt)
+		require.Nil(t, err)
+		addr, err := priv.PublicKey().Address()
+		require.Nil(t, err)
+
+		_, path := startServer(t, priv)
+		info, err := os.Stat(path)
+		require.Nil(t, err)
+		require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		client, err := Dial(context.Background(), path)
+		require.Nil(t, err)
+
+		clientAddr, err := client.PublicKey().Address()
+		require.Nil(t, err)
+		require.Equal(t, addr, clientAddr)
+
+		for _, signer := range []crypto.Signer{client, asym.NewSigner(priv)} {
+			sig, err := asym.SignWithSigner(context.Background(), signer, digest[:])
+			require.Nil(t, err)
+			ok, err := asym.VerifyWithType(sig, digest[:], *addr)
+			require.Nil(t, err)
+			require.True(t, ok)
+		}
+
+		require.Nil(t, client.Close())
+	}
+}
+
+func TestSignerError(t *testing.T) {
+	priv, err := asym.GenerateKeyPair(crypto.Secp256k1)
+	require.Nil(t, err)
+
+	server, path := startServer(t, priv)
+	client, err := Dial(context.Background(), path)
+	require.Nil(t, err)
+	defer client.Close()
+
+	// Secp256k1 only signs 32 byte digests, the connection stays usable
+	_, err = client.Sign(context.Background(), []byte("short"))
+	require.NotNil(t, err)
+	digest := sha256.Sum256([]byte("bitxhub"))
+	_, err = client.Sign(context.Background(), digest[:])
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = asym.NewSigner(priv).Sign(ctx, digest[:])
+	require.Equal(t, context.Canceled, err)
+
+	require.Nil(t, server.Close())
+	_, err = client.Sign(context.Background(), digest[:])
+	require.NotNil(t, err)
+
+	_, err = Dial(context.Background(), path)
+	require.NotNil(t, err)
+}
+
+// wrongSigner signs another digest than the one asked for
+type wrongSigner struct {
+	crypto.PrivateKey
+}
+
+func (k wrongSigner) Sign(digest []byte) ([]byte, error) {
+	other := sha256.Sum256(digest)
+	return k.PrivateKey.Sign(other[:])
+}
+
+func TestSignerVerify(t *testing.T) {
+	priv, err := asym.GenerateKeyPair(crypto.Ed25519)
+	require.Nil(t, err)
+
+	_, path := startServer(t, wrongSigner{priv})
+	client, err := Dial(context.Background(), path)
+	require.Nil(t, err)
+	defer client.Close()
+
+	digest := sha256.Sum256([]byte("bitxhub"))
+	_, err = client.Sign(context.Background(), digest[:])
+	require.NotNil(t, err)
+
+	// A second server can't take over the socket of a running one
+	require.NotNil(t, NewServer(priv).ListenAndServe(path))
+	files, err := ioutil.ReadDir(filepath.Dir(path))
+	require.Nil(t, err)
+	require.Len(t, files, 1)
+}
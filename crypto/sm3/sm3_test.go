@@ -0,0 +1,40 @@
+package sm3
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSum(t *testing.T) {
+	// Test vectors from GB/T 32905-2016 appendix A
+	cases := map[string]string{
+		"abc":                      "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0",
+		strings.Repeat("abcd", 16): "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732",
+	}
+	for msg, expected := range cases {
+		sum := Sum([]byte(msg))
+		require.Equal(t, expected, hex.EncodeToString(sum[:]))
+	}
+}
+
+func TestWrite(t *testing.T) {
+	msg := []byte(strings.Repeat("hyperchain", 20))
+	expected := Sum(msg)
+
+	h := New()
+	for i := range msg {
+		_, err := h.Write(msg[i : i+1])
+		require.Nil(t, err)
+	}
+	require.Equal(t, expected[:], h.Sum(nil))
+	// Sum must not change the state
+	require.Equal(t, expected[:], h.Sum(nil))
+
+	h.Reset()
+	_, err := h.Write(msg)
+	require.Nil(t, err)
+	require.Equal(t, expected[:], h.Sum(nil))
+}
@@ -0,0 +1,26 @@
+package sym
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// aeadSeal encrypts plain with a random nonce, which is prepended to the output
+func aeadSeal(aead cipher.AEAD, plain, ad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plain, ad), nil
+}
+
+// aeadOpen decrypts the output of aeadSeal
+func aeadOpen(aead cipher.AEAD, crypted, ad []byte) ([]byte, error) {
+	if len(crypted) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("decrypt failed,please check it")
+	}
+
+	return aead.Open(nil, crypted[:aead.NonceSize()], crypted[aead.NonceSize():], ad)
+}
@@ -0,0 +1,62 @@
+package sym
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+)
+
+var _ crypto.AEADKey = (*AESGCMKey)(nil)
+
+// AESGCMKey a AES-256 instance in GCM mode, the ciphertext is authenticated
+type AESGCMKey struct {
+	key []byte
+}
+
+// Bytes return bytes
+func (gk *AESGCMKey) Bytes() ([]byte, error) {
+	r := make([]byte, len(gk.key))
+	copy(r, gk.key)
+	return r, nil
+}
+
+// Encrypt encrypts plain text, the output is nonce || ciphertext || tag
+func (gk *AESGCMKey) Encrypt(plain []byte) ([]byte, error) {
+	return gk.EncryptWithAD(plain, nil)
+}
+
+// Decrypt decrypts and authenticates the output of Encrypt
+func (gk *AESGCMKey) Decrypt(crypted []byte) ([]byte, error) {
+	return gk.DecryptWithAD(crypted, nil)
+}
+
+// EncryptWithAD encrypts plain text and authenticates it together with ad
+func (gk *AESGCMKey) EncryptWithAD(plain, ad []byte) ([]byte, error) {
+	aead, err := gk.aead()
+	if err != nil {
+		return nil, err
+	}
+	return aeadSeal(aead, plain, ad)
+}
+
+// DecryptWithAD decrypts the output of EncryptWithAD
+func (gk *AESGCMKey) DecryptWithAD(crypted, ad []byte) ([]byte, error) {
+	aead, err := gk.aead()
+	if err != nil {
+		return nil, err
+	}
+	return aeadOpen(aead, crypted, ad)
+}
+
+func (gk *AESGCMKey) Type() crypto.KeyType {
+	return crypto.AES_GCM
+}
+
+func (gk *AESGCMKey) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(gk.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,52 @@
+package sym
+
+import (
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+var _ crypto.AEADKey = (*ChaCha20Poly1305Key)(nil)
+
+// ChaCha20Poly1305Key a ChaCha20-Poly1305 instance, the ciphertext is authenticated
+type ChaCha20Poly1305Key struct {
+	key []byte
+}
+
+// Bytes return bytes
+func (ck *ChaCha20Poly1305Key) Bytes() ([]byte, error) {
+	r := make([]byte, len(ck.key))
+	copy(r, ck.key)
+	return r, nil
+}
+
+// Encrypt encrypts plain text, the output is nonce || ciphertext || tag
+func (ck *ChaCha20Poly1305Key) Encrypt(plain []byte) ([]byte, error) {
+	return ck.EncryptWithAD(plain, nil)
+}
+
+// Decrypt decrypts and authenticates the output of Encrypt
+func (ck *ChaCha20Poly1305Key) Decrypt(crypted []byte) ([]byte, error) {
+	return ck.DecryptWithAD(crypted, nil)
+}
+
+// EncryptWithAD encrypts plain text and authenticates it together with ad
+func (ck *ChaCha20Poly1305Key) EncryptWithAD(plain, ad []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(ck.key)
+	if err != nil {
+		return nil, err
+	}
+	return aeadSeal(aead, plain, ad)
+}
+
+// DecryptWithAD decrypts the output of EncryptWithAD
+func (ck *ChaCha20Poly1305Key) DecryptWithAD(crypted, ad []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(ck.key)
+	if err != nil {
+		return nil, err
+	}
+	return aeadOpen(aead, crypted, ad)
+}
+
+func (ck *ChaCha20Poly1305Key) Type() crypto.KeyType {
+	return crypto.ChaCha20Poly1305
+}
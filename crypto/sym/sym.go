@@ -5,13 +5,16 @@ import (
 	"fmt"
 
 	"github.com/meshplus/bitxhub-kit/crypto"
+	"golang.org/x/crypto/chacha20poly1305"
 )
 
 var (
 	aesKeyLengthError = errors.New("the secret len must be 32")
 )
 
-// GenerateSymKey generates a new aes256 key or 3des key
+// GenerateSymKey generates a new symmetric key. AES and ThirdDES use the
+// unauthenticated CBC mode and are kept for compatibility, new code should
+// prefer AES_GCM or ChaCha20Poly1305.
 func GenerateSymKey(opt crypto.KeyType, key []byte) (crypto.SymmetricKey, error) {
 	switch opt {
 	case crypto.AES:
@@ -21,6 +24,21 @@ func GenerateSymKey(opt crypto.KeyType, key []byte) (crypto.SymmetricKey, error)
 		return &AESKey{key: key}, nil
 	case crypto.ThirdDES:
 		return &ThirdDESKey{key: key}, nil
+	case crypto.AES_GCM:
+		if len(key) != 32 {
+			return nil, aesKeyLengthError
+		}
+		return &AESGCMKey{key: key}, nil
+	case crypto.ChaCha20Poly1305:
+		if len(key) != chacha20poly1305.KeySize {
+			return nil, aesKeyLengthError
+		}
+		return &ChaCha20Poly1305Key{key: key}, nil
+	case crypto.SM4:
+		if len(key) != 16 {
+			return nil, sm4KeyLengthError
+		}
+		return &SM4Key{key: key}, nil
 	default:
 		return nil, fmt.Errorf("wrong symmetric algorithm")
 	}
@@ -2,6 +2,7 @@ package sym
 
 import (
 	"crypto/rand"
+	"encoding/hex"
 	"testing"
 
 	"github.com/meshplus/bitxhub-kit/crypto"
@@ -50,3 +51,77 @@ func TestThirdDESKey(t *testing.T) {
 
 	require.Equal(t, string(o), msg)
 }
+
+func TestSM4(t *testing.T) {
+	key := make([]byte, 16)
+	_, err := rand.Read(key)
+	require.Nil(t, err)
+	sm4, err := GenerateSymKey(crypto.SM4, key)
+	require.Nil(t, err)
+
+	c, err := sm4.Encrypt([]byte(msg))
+	require.Nil(t, err)
+
+	o, err := sm4.Decrypt(c)
+	require.Nil(t, err)
+
+	require.Equal(t, string(o), msg)
+
+	_, err = GenerateSymKey(crypto.SM4, make([]byte, 32))
+	require.Equal(t, sm4KeyLengthError, err)
+}
+
+func TestSM4Cipher(t *testing.T) {
+	// Test vector from GB/T 32907-2016 appendix A
+	key, err := hex.DecodeString("0123456789abcdeffedcba9876543210")
+	require.Nil(t, err)
+	block, err := NewSM4Cipher(key)
+	require.Nil(t, err)
+
+	dst := make([]byte, 16)
+	block.Encrypt(dst, key)
+	require.Equal(t, "681edf34d206965e86b3e94f536e4246", hex.EncodeToString(dst))
+
+	block.Decrypt(dst, dst)
+	require.Equal(t, key, dst)
+}
+
+func TestAEAD(t *testing.T) {
+	testAEAD(t, crypto.AES_GCM)
+	testAEAD(t, crypto.ChaCha20Poly1305)
+}
+
+func testAEAD(t *testing.T, opt crypto.KeyType) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.Nil(t, err)
+	symKey, err := GenerateSymKey(opt, key)
+	require.Nil(t, err)
+	aead, ok := symKey.(crypto.AEADKey)
+	require.True(t, ok)
+
+	c, err := aead.Encrypt([]byte(msg))
+	require.Nil(t, err)
+	o, err := aead.Decrypt(c)
+	require.Nil(t, err)
+	require.Equal(t, msg, string(o))
+
+	// Tampered ciphertext must be rejected
+	c[len(c)/2] ^= 0x01
+	_, err = aead.Decrypt(c)
+	require.NotNil(t, err)
+	_, err = aead.Decrypt(c[:10])
+	require.NotNil(t, err)
+
+	ad := []byte("bitxhub")
+	c, err = aead.EncryptWithAD([]byte(msg), ad)
+	require.Nil(t, err)
+	o, err = aead.DecryptWithAD(c, ad)
+	require.Nil(t, err)
+	require.Equal(t, msg, string(o))
+	_, err = aead.DecryptWithAD(c, []byte("bitxhub1"))
+	require.NotNil(t, err)
+
+	_, err = GenerateSymKey(opt, key[:12])
+	require.Equal(t, aesKeyLengthError, err)
+}
@@ -0,0 +1,170 @@
+package threshold
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// point is an affine curve point, nil is the point at infinity.
+type point struct {
+	x, y *big.Int
+}
+
+func newPoint(x, y *big.Int) *point {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil
+	}
+	return &point{x: x, y: y}
+}
+
+// basePoint returns k*G
+func basePoint(curve elliptic.Curve, k *big.Int) *point {
+	k = new(big.Int).Mod(k, curve.Params().N)
+	if k.Sign() == 0 {
+		return nil
+	}
+	return newPoint(curve.ScalarBaseMult(scalarBytes(k, curve)))
+}
+
+// mul returns k*p
+func (p *point) mul(curve elliptic.Curve, k *big.Int) *point {
+	k = new(big.Int).Mod(k, curve.Params().N)
+	if p == nil || k.Sign() == 0 {
+		return nil
+	}
+	return newPoint(curve.ScalarMult(p.x, p.y, scalarBytes(k, curve)))
+}
+
+// addPoints returns p+q
+func addPoints(curve elliptic.Curve, p, q *point) *point {
+	switch {
+	case p == nil:
+		return q
+	case q == nil:
+		return p
+	case p.equal(q):
+		return newPoint(curve.Double(p.x, p.y))
+	}
+	return newPoint(curve.Add(p.x, p.y, q.x, q.y))
+}
+
+func (p *point) equal(q *point) bool {
+	if p == nil || q == nil {
+		return p == q
+	}
+	return p.x.Cmp(q.x) == 0 && p.y.Cmp(q.y) == 0
+}
+
+// marshal encodes the point uncompressed, the point at infinity is empty.
+func (p *point) marshal(curve elliptic.Curve) []byte {
+	if p == nil {
+		return []byte{}
+	}
+	return elliptic.Marshal(curve, p.x, p.y)
+}
+
+func unmarshalPoint(curve elliptic.Curve, data []byte) (*point, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	x, y := elliptic.Unmarshal(curve, data)
+	if x == nil {
+		return nil, fmt.Errorf("invalid point")
+	}
+	return &point{x: x, y: y}, nil
+}
+
+func marshalPoints(curve elliptic.Curve, points []*point) [][]byte {
+	ret := make([][]byte, len(points))
+	for i, p := range points {
+		ret[i] = p.marshal(curve)
+	}
+	return ret
+}
+
+func unmarshalPoints(curve elliptic.Curve, data [][]byte) ([]*point, error) {
+	ret := make([]*point, len(data))
+	for i, d := range data {
+		p, err := unmarshalPoint(curve, d)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = p
+	}
+	return ret, nil
+}
+
+// commit returns the Feldman commitments to the coefficients of poly.
+func commit(curve elliptic.Curve, poly polynomial) []*point {
+	ret := make([]*point, len(poly))
+	for i, c := range poly {
+		ret[i] = basePoint(curve, c)
+	}
+	return ret
+}
+
+// evalCommitments returns the commitment to the share of id, the sum of
+// commitments[i] * id^i.
+func evalCommitments(curve elliptic.Curve, commitments []*point, id uint32) *point {
+	var ret *point
+	x := new(big.Int).SetUint64(uint64(id))
+	for i := len(commitments) - 1; i >= 0; i-- {
+		ret = addPoints(curve, ret.mul(curve, x), commitments[i])
+	}
+	return ret
+}
+
+// dleqProof proves that log_G(A) = log_P(W) without revealing the logarithm.
+// It is a Chaum-Pedersen proof made non-interactive with the Fiat-Shamir
+// transform.
+type dleqProof struct {
+	C *big.Int `json:"c"`
+	Z *big.Int `json:"z"`
+}
+
+// proveDLEQ proves that W = a*P for A = a*G, bound to the given context.
+func proveDLEQ(curve elliptic.Curve, context string, a *big.Int, p *point) (*dleqProof, error) {
+	n := curve.Params().N
+	w, err := randomScalar(n)
+	if err != nil {
+		return nil, err
+	}
+
+	c := dleqChallenge(curve, context, p, basePoint(curve, a), p.mul(curve, a), basePoint(curve, w), p.mul(curve, w))
+	z := new(big.Int).Mul(c, a)
+	z.Sub(w, z)
+	z.Mod(z, n)
+	return &dleqProof{C: c, Z: z}, nil
+}
+
+// verify checks that the proof shows W = a*P for A = a*G.
+func (proof *dleqProof) verify(curve elliptic.Curve, context string, pa, p, w *point) error {
+	n := curve.Params().N
+	if proof == nil || proof.C == nil || proof.Z == nil ||
+		proof.C.Sign() < 0 || proof.C.Cmp(n) >= 0 || proof.Z.Sign() < 0 || proof.Z.Cmp(n) >= 0 {
+		return fmt.Errorf("malformed proof")
+	}
+
+	// t1 = z*G + c*A, t2 = z*P + c*W
+	t1 := addPoints(curve, basePoint(curve, proof.Z), pa.mul(curve, proof.C))
+	t2 := addPoints(curve, p.mul(curve, proof.Z), w.mul(curve, proof.C))
+	if dleqChallenge(curve, context, p, pa, w, t1, t2).Cmp(proof.C) != 0 {
+		return fmt.Errorf("invalid proof")
+	}
+	return nil
+}
+
+func dleqChallenge(curve elliptic.Curve, context string, points ...*point) *big.Int {
+	hash := sha256.New()
+	var buf [4]byte
+	for _, data := range append([][]byte{[]byte(context)}, marshalPoints(curve, points)...) {
+		binary.BigEndian.PutUint32(buf[:], uint32(len(data)))
+		hash.Write(buf[:])
+		hash.Write(data)
+	}
+	c := new(big.Int).SetBytes(hash.Sum(nil))
+	return c.Mod(c, curve.Params().N)
+}
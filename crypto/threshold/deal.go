@@ -0,0 +1,199 @@
+package threshold
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// dealMsg is what a dealer sends to one party, the Feldman commitments to
+// the coefficients of all its polynomials and the shares of the receiver.
+// The commitment to the constant term of a zero polynomial is empty.
+type dealMsg struct {
+	Commitments [][][]byte `json:"commitments"`
+	Shares      []*big.Int `json:"shares"`
+}
+
+// echoMsg holds the hashes of the commitments a party received from every
+// dealer, so that no dealer can commit to different polynomials towards
+// different parties.
+type echoMsg struct {
+	Hashes map[uint32][]byte `json:"hashes"`
+}
+
+// jointRandom lets every party deal a random polynomial of each of the given
+// degrees and sums the dealt shares, so that no party knows the shared
+// values. The polynomials flagged in zero share 0. It takes the rounds num
+// and num+1 and returns the own shares and the commitments to the summed
+// polynomials, the first of which commits to the public point of the first
+// shared value.
+func jointRandom(r *round, num int, curve elliptic.Curve, degrees []int, zero []bool) ([]*big.Int, [][]*point, error) {
+	n := curve.Params().N
+	polys := make([]polynomial, len(degrees))
+	commitments := make([][]*point, len(degrees))
+	own := make([][][]byte, len(degrees))
+	for i, degree := range degrees {
+		secret := new(big.Int)
+		if !zero[i] {
+			k, err := randomScalar(n)
+			if err != nil {
+				return nil, nil, err
+			}
+			secret = k
+		}
+		poly, err := newPolynomial(secret, degree, n)
+		if err != nil {
+			return nil, nil, err
+		}
+		polys[i] = poly
+		commitments[i] = commit(curve, poly)
+		own[i] = marshalPoints(curve, commitments[i])
+	}
+
+	payloads := make(map[uint32]interface{}, len(r.peers))
+	for _, peer := range r.peers {
+		msg := &dealMsg{Commitments: own, Shares: make([]*big.Int, len(polys))}
+		for i, poly := range polys {
+			msg.Shares[i] = poly.eval(peer, n)
+		}
+		payloads[peer] = msg
+	}
+
+	shares := make([]*big.Int, len(polys))
+	for i, poly := range polys {
+		shares[i] = poly.eval(r.id, n)
+	}
+
+	received, err := r.exchange(num, payloads)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := map[uint32][]byte{r.id: commitmentsHash(own)}
+	for from, data := range received {
+		msg := &dealMsg{}
+		if err := json.Unmarshal(data, msg); err != nil {
+			return nil, nil, abort(from, "invalid deal: %w", err)
+		}
+		dealt, err := verifyDeal(curve, r.id, degrees, zero, msg)
+		if err != nil {
+			return nil, nil, abort(from, "invalid deal: %w", err)
+		}
+		for i, share := range msg.Shares {
+			shares[i].Add(shares[i], share)
+			shares[i].Mod(shares[i], n)
+			for j, c := range dealt[i] {
+				commitments[i][j] = addPoints(curve, commitments[i][j], c)
+			}
+		}
+		hashes[from] = commitmentsHash(msg.Commitments)
+	}
+
+	// Echo the received commitments to make sure everyone got the same
+	received, err = r.exchange(num+1, broadcast(r.peers, &echoMsg{Hashes: hashes}))
+	if err != nil {
+		return nil, nil, err
+	}
+	var conflicts [][]uint32
+	for from, data := range received {
+		msg := &echoMsg{}
+		if err := json.Unmarshal(data, msg); err != nil {
+			return nil, nil, abort(from, "invalid echo: %w", err)
+		}
+		if len(msg.Hashes) != len(hashes) {
+			return nil, nil, abort(from, "invalid echo")
+		}
+		for dealer, hash := range hashes {
+			if bytes.Equal(msg.Hashes[dealer], hash) {
+				continue
+			}
+			// The echoes are not signed, so either the dealer sent other
+			// commitments to the echoer or the echoer lies about them. Only
+			// if the echoer contradicts itself or our own deal it is certain.
+			if dealer == from || dealer == r.id {
+				conflicts = append(conflicts, []uint32{from})
+			} else {
+				conflicts = append(conflicts, []uint32{dealer, from})
+			}
+		}
+	}
+	if len(conflicts) != 0 {
+		return nil, nil, &AbortError{
+			Parties: suspects(conflicts),
+			Err:     fmt.Errorf("parties received different commitments"),
+		}
+	}
+
+	return shares, commitments, nil
+}
+
+// suspects returns the parties involved in every conflict, which is the
+// party that deviated if a single one did, or else all involved parties.
+func suspects(conflicts [][]uint32) []uint32 {
+	count := make(map[uint32]int)
+	for _, conflict := range conflicts {
+		for _, id := range conflict {
+			count[id]++
+		}
+	}
+
+	var all, common []uint32
+	for id, c := range count {
+		all = append(all, id)
+		if c == len(conflicts) {
+			common = append(common, id)
+		}
+	}
+	if len(common) == 0 {
+		common = all
+	}
+	sort.Slice(common, func(i, j int) bool { return common[i] < common[j] })
+	return common
+}
+
+// verifyDeal checks the shares of a deal against the commitments and
+// returns the commitments.
+func verifyDeal(curve elliptic.Curve, id uint32, degrees []int, zero []bool, msg *dealMsg) ([][]*point, error) {
+	if len(msg.Shares) != len(degrees) || len(msg.Commitments) != len(degrees) {
+		return nil, fmt.Errorf("expect %d polynomials", len(degrees))
+	}
+
+	n := curve.Params().N
+	ret := make([][]*point, len(degrees))
+	for i, degree := range degrees {
+		share := msg.Shares[i]
+		if share == nil || share.Sign() < 0 || share.Cmp(n) >= 0 {
+			return nil, fmt.Errorf("share %d out of range", i)
+		}
+		if len(msg.Commitments[i]) != degree+1 {
+			return nil, fmt.Errorf("polynomial %d has %d commitments, expect %d", i, len(msg.Commitments[i]), degree+1)
+		}
+		points, err := unmarshalPoints(curve, msg.Commitments[i])
+		if err != nil {
+			return nil, fmt.Errorf("polynomial %d: %w", i, err)
+		}
+		if (points[0] == nil) != zero[i] {
+			return nil, fmt.Errorf("polynomial %d has a wrong constant term", i)
+		}
+		if !basePoint(curve, share).equal(evalCommitments(curve, points, id)) {
+			return nil, fmt.Errorf("share %d does not match the commitments", i)
+		}
+		ret[i] = points
+	}
+	return ret, nil
+}
+
+func commitmentsHash(commitments [][][]byte) []byte {
+	hash := sha256.New()
+	for _, poly := range commitments {
+		for _, c := range poly {
+			hash.Write([]byte{byte(len(c))})
+			hash.Write(c)
+		}
+		hash.Write([]byte{0xff})
+	}
+	return hash.Sum(nil)
+}
@@ -0,0 +1,41 @@
+package threshold
+
+import (
+	"context"
+	"fmt"
+)
+
+// KeyGen runs the distributed key generation for the party cfg.ID. All
+// parties of the committee have to run it with the same config and a
+// session id which is unique on the transport.
+func KeyGen(ctx context.Context, cfg Config, session string, transport Transport) (*KeyShare, error) {
+	curve, err := curveOf(cfg.Curve)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkParties(cfg.Parties, cfg.ID); err != nil {
+		return nil, err
+	}
+	if cfg.Threshold < 1 || 2*cfg.Threshold+1 > len(cfg.Parties) {
+		return nil, fmt.Errorf("threshold %d needs at least %d parties", cfg.Threshold, 2*cfg.Threshold+1)
+	}
+
+	r := newRound(ctx, transport, session, cfg.ID, cfg.Parties)
+	shares, commitments, err := jointRandom(r, 1, curve, []int{cfg.Threshold}, []bool{false})
+	if err != nil {
+		return nil, err
+	}
+	if commitments[0][0] == nil {
+		return nil, fmt.Errorf("shared public key is the identity, retry with another session")
+	}
+
+	return &KeyShare{
+		Curve:       cfg.Curve,
+		Threshold:   cfg.Threshold,
+		Parties:     sortedCopy(cfg.Parties),
+		ID:          cfg.ID,
+		Share:       shares[0],
+		PublicKey:   commitments[0][0].marshal(curve),
+		Commitments: marshalPoints(curve, commitments[0]),
+	}, nil
+}
@@ -0,0 +1,222 @@
+package threshold
+
+import (
+	"bytes"
+	"context"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/ecdsa"
+)
+
+// Sign signs digest together with the other signers, at least 2t+1 parties
+// of the committee. All signers have to run it with the same signers, digest
+// and a session id which is unique on the transport.
+//
+// The signature has the format of ecdsa.PrivateKey.Sign for the curve, so
+// it verifies with ecdsa.PublicKey.Verify and asym.Verify.
+func Sign(ctx context.Context, share *KeyShare, signers []uint32, session string, digest []byte, transport Transport) ([]byte, error) {
+	curve, err := curveOf(share.Curve)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkParties(signers, share.ID); err != nil {
+		return nil, err
+	}
+	if len(signers) < 2*share.Threshold+1 {
+		return nil, fmt.Errorf("signing needs at least %d signers", 2*share.Threshold+1)
+	}
+	for _, signer := range signers {
+		if !containsParty(share.Parties, signer) {
+			return nil, fmt.Errorf("signer %d is not in the committee", signer)
+		}
+	}
+	if share.Curve == crypto.Secp256k1 && len(digest) != ecdsa.DigestLength {
+		return nil, fmt.Errorf("hash is required to be exactly %d bytes (%d)", ecdsa.DigestLength, len(digest))
+	}
+	pub, err := share.PubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	keyCommitments, err := share.commitments(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	n := curve.Params().N
+	r := newRound(ctx, transport, session, share.ID, signers)
+
+	// Rounds 1 and 2: share the nonce k, a blinding value a and two zero
+	// values, which randomise the products of shares.
+	t := share.Threshold
+	shares, commitments, err := jointRandom(r, 1, curve, []int{t, t, 2 * t, 2 * t}, []bool{false, false, true, true})
+	if err != nil {
+		return nil, err
+	}
+	k, a, z1, z2 := shares[0], shares[1], shares[2], shares[3]
+	nonce := commitments[0][0]
+	if nonce == nil {
+		return nil, fmt.Errorf("invalid nonce, retry with another session")
+	}
+	sigR := new(big.Int).Mod(nonce.x, n)
+	if sigR.Sign() == 0 {
+		return nil, fmt.Errorf("invalid nonce, retry with another session")
+	}
+
+	// Round 3: open mu = k*a, the share of party i satisfies
+	// mu_i*G = a_i*K_i + Z1_i
+	v := new(big.Int).Mul(k, a)
+	v.Add(v, z1)
+	v.Mod(v, n)
+	mu, err := open(r, 3, curve, v, a, commitments[1], func(id uint32) *point {
+		return evalCommitments(curve, commitments[0], id)
+	}, func(id uint32, w *point) *point {
+		return addPoints(curve, w, evalCommitments(curve, commitments[2], id))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if mu.Sign() == 0 {
+		return nil, fmt.Errorf("invalid blinding value, retry with another session")
+	}
+
+	// Round 4: open s = k^-1 * (m + r*x), as a*mu^-1 is a share of k^-1. The
+	// share of party i satisfies s_i*G = mu^-1*(m*A_i + r*a_i*X_i) + Z2_i
+	m := hashToInt(digest, n)
+	muInv := new(big.Int).ModInverse(mu, n)
+	s := new(big.Int).Mul(sigR, share.Share)
+	s.Add(s, m)
+	s.Mul(s, a)
+	s.Mul(s, muInv)
+	s.Add(s, z2)
+	s.Mod(s, n)
+	sigS, err := open(r, 4, curve, s, a, commitments[1], func(id uint32) *point {
+		return evalCommitments(curve, keyCommitments, id)
+	}, func(id uint32, w *point) *point {
+		p := addPoints(curve, evalCommitments(curve, commitments[1], id).mul(curve, m), w.mul(curve, sigR))
+		return addPoints(curve, p.mul(curve, muInv), evalCommitments(curve, commitments[3], id))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sigS.Sign() == 0 {
+		return nil, fmt.Errorf("invalid signature, retry with another session")
+	}
+
+	var sig []byte
+	if share.Curve == crypto.Secp256k1 {
+		recID := byte(nonce.y.Bit(0))
+		if sigS.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+			sigS.Sub(n, sigS)
+			recID ^= 1
+		}
+		sig = make([]byte, ecdsa.SignatureLength)
+		copy(sig[:32], ecdsa.PaddedBigBytes(sigR, 32))
+		copy(sig[32:64], ecdsa.PaddedBigBytes(sigS, 32))
+		sig[ecdsa.RecoveryIDOffset] = recID
+
+		recovered, err := ecdsa.Ecrecover(digest, sig)
+		if err != nil || !bytes.Equal(recovered, share.PublicKey) {
+			return nil, fmt.Errorf("threshold signature does not recover the shared public key")
+		}
+	} else {
+		pubBytes, err := pub.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		sig, err = asn1.Marshal(ecdsa.Sig{Pub: pubBytes, R: sigR, S: sigS})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := pub.Verify(digest, sig); err != nil {
+		return nil, fmt.Errorf("verify threshold signature: %w", err)
+	}
+	return sig, nil
+}
+
+// openMsg is the share of a value being opened, with W = a*P for the share
+// a of the blinding value and a point P of the sender, and a proof of W.
+type openMsg struct {
+	Share *big.Int   `json:"share"`
+	Point []byte     `json:"point"`
+	Proof *dleqProof `json:"proof"`
+}
+
+// open publishes the own share of a value of degree 2t and interpolates the
+// value from the shares of all signers. The share of every signer comes with
+// W = a*base(id) for its share a of the blinding value committed to in
+// aCommitments, it must satisfy share*G = expect(id, W).
+func open(r *round, num int, curve elliptic.Curve, share, a *big.Int, aCommitments []*point,
+	base func(id uint32) *point, expect func(id uint32, w *point) *point) (*big.Int, error) {
+	p := base(r.id)
+	proof, err := proveDLEQ(curve, proofContext(r, num, r.id), a, p)
+	if err != nil {
+		return nil, err
+	}
+	own := &openMsg{Share: share, Point: p.mul(curve, a).marshal(curve), Proof: proof}
+	received, err := r.exchange(num, broadcast(r.peers, own))
+	if err != nil {
+		return nil, err
+	}
+
+	n := curve.Params().N
+	shares := map[uint32]*big.Int{r.id: share}
+	for from, data := range received {
+		msg := &openMsg{}
+		if err := json.Unmarshal(data, msg); err != nil {
+			return nil, abort(from, "invalid share of round %d: %w", num, err)
+		}
+		if msg.Share == nil || msg.Share.Sign() < 0 || msg.Share.Cmp(n) >= 0 {
+			return nil, abort(from, "share of round %d out of range", num)
+		}
+		w, err := unmarshalPoint(curve, msg.Point)
+		if err != nil {
+			return nil, abort(from, "invalid share of round %d: %w", num, err)
+		}
+		if err := msg.Proof.verify(curve, proofContext(r, num, from), evalCommitments(curve, aCommitments, from), base(from), w); err != nil {
+			return nil, abort(from, "share of round %d: %w", num, err)
+		}
+		if !basePoint(curve, msg.Share).equal(expect(from, w)) {
+			return nil, abort(from, "share of round %d does not match the commitments", num)
+		}
+		shares[from] = msg.Share
+	}
+	return interpolate(shares, n), nil
+}
+
+// proofContext binds a proof to the session, round and prover
+func proofContext(r *round, num int, id uint32) string {
+	return fmt.Sprintf("%s/%d/%d", r.session, num, id)
+}
+
+// hashToInt converts a hash value to an integer like crypto/ecdsa does.
+func hashToInt(hash []byte, n *big.Int) *big.Int {
+	orderBits := n.BitLen()
+	orderBytes := (orderBits + 7) / 8
+	if len(hash) > orderBytes {
+		hash = hash[:orderBytes]
+	}
+
+	ret := new(big.Int).SetBytes(hash)
+	excess := len(hash)*8 - orderBits
+	if excess > 0 {
+		ret.Rsh(ret, uint(excess))
+	}
+	return ret
+}
+
+func containsParty(parties []uint32, id uint32) bool {
+	for _, p := range parties {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
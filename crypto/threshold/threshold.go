@@ -0,0 +1,310 @@
+// Package threshold implements distributed key generation and t-of-n ECDSA
+// signing for the curves of crypto/asym/ecdsa. No party ever holds the full
+// private key, the signatures are ordinary ECDSA signatures of the shared
+// public key.
+//
+// Keys are shared with polynomials of degree t, so that t parties learn
+// nothing about the key. Signing follows Gennaro, Jarecki, Krawczyk and
+// Rabin, "Robust threshold DSS signatures", and multiplies shared values,
+// which needs 2t+1 signers.
+//
+// Every dealt share is checked against Feldman commitments, which the parties
+// echo to each other to make sure that every dealer committed to the same
+// polynomials towards everyone. Every opened value comes with a proof that it
+// was computed from the committed shares. A party deviating from the
+// protocol makes the others abort with an AbortError naming it, or naming
+// the few parties among which it is when the others can't tell who lied. The
+// protocols don't recover, the others have to start a new session without
+// the party.
+package threshold
+
+import (
+	"bytes"
+	"context"
+	ecdsa2 "crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/ecdsa"
+)
+
+// KeyShare is the share of a party in a distributed private key.
+type KeyShare struct {
+	Curve     crypto.KeyType `json:"curve"`
+	Threshold int            `json:"threshold"`
+	Parties   []uint32       `json:"parties"`
+	ID        uint32         `json:"id"`
+	Share     *big.Int       `json:"share"`
+	// PublicKey is the uncompressed shared public key
+	PublicKey []byte `json:"public_key"`
+	// Commitments are the Feldman commitments to the coefficients of the
+	// sharing polynomial, which give the public key share of every party.
+	Commitments [][]byte `json:"commitments"`
+}
+
+// AbortError is returned when a party deviates from the protocol.
+type AbortError struct {
+	// Parties are the ids of the suspected parties. When the evidence can't
+	// tell which of several parties deviated, all of them are named, at
+	// least one of them is offending.
+	Parties []uint32
+	Err     error
+}
+
+func (e *AbortError) Error() string {
+	if len(e.Parties) == 1 {
+		return fmt.Sprintf("party %d: %v", e.Parties[0], e.Err)
+	}
+	return fmt.Sprintf("one of parties %v: %v", e.Parties, e.Err)
+}
+
+func (e *AbortError) Unwrap() error {
+	return e.Err
+}
+
+func abort(party uint32, format string, a ...interface{}) error {
+	return &AbortError{Parties: []uint32{party}, Err: fmt.Errorf(format, a...)}
+}
+
+// Config describes a committee and the party running the protocol.
+type Config struct {
+	Curve crypto.KeyType
+	// Threshold is the number of parties that can't learn anything about the
+	// key, it must be less than half of the parties.
+	Threshold int
+	// Parties holds the non zero ids of all parties
+	Parties []uint32
+	ID      uint32
+}
+
+func curveOf(typ crypto.KeyType) (elliptic.Curve, error) {
+	switch typ {
+	case crypto.Secp256k1:
+		return ecdsa.S256(), nil
+	case crypto.ECDSA_P256:
+		return elliptic.P256(), nil
+	case crypto.ECDSA_P384:
+		return elliptic.P384(), nil
+	case crypto.ECDSA_P521:
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("threshold signing is not supported for key type %d", typ)
+	}
+}
+
+// PubKey returns the shared public key.
+func (k *KeyShare) PubKey() (*ecdsa.PublicKey, error) {
+	curve, err := curveOf(k.Curve)
+	if err != nil {
+		return nil, err
+	}
+	x, y := elliptic.Unmarshal(curve, k.PublicKey)
+	if x == nil {
+		return nil, fmt.Errorf("invalid shared public key")
+	}
+	return ecdsa.NewPublicKey(ecdsa2.PublicKey{Curve: curve, X: x, Y: y})
+}
+
+// commitments returns the commitments to the sharing polynomial, the first
+// of which is the shared public key.
+func (k *KeyShare) commitments(curve elliptic.Curve) ([]*point, error) {
+	if len(k.Commitments) != k.Threshold+1 {
+		return nil, fmt.Errorf("key share has %d commitments, expect %d", len(k.Commitments), k.Threshold+1)
+	}
+	commitments, err := unmarshalPoints(curve, k.Commitments)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key share commitments: %w", err)
+	}
+	if !bytes.Equal(commitments[0].marshal(curve), k.PublicKey) {
+		return nil, fmt.Errorf("key share commitments don't match the public key")
+	}
+	return commitments, nil
+}
+
+// polynomial holds the coefficients of a polynomial over the scalar field,
+// starting with the constant term.
+type polynomial []*big.Int
+
+func randomScalar(n *big.Int) (*big.Int, error) {
+	for {
+		k, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return nil, err
+		}
+		if k.Sign() != 0 {
+			return k, nil
+		}
+	}
+}
+
+// newPolynomial returns a random polynomial of the given degree with the
+// constant term secret.
+func newPolynomial(secret *big.Int, degree int, n *big.Int) (polynomial, error) {
+	poly := make(polynomial, degree+1)
+	poly[0] = secret
+	for i := 1; i <= degree; i++ {
+		c, err := randomScalar(n)
+		if err != nil {
+			return nil, err
+		}
+		poly[i] = c
+	}
+	return poly, nil
+}
+
+func (p polynomial) eval(x uint32, n *big.Int) *big.Int {
+	ret := new(big.Int)
+	xi := new(big.Int).SetUint64(uint64(x))
+	for i := len(p) - 1; i >= 0; i-- {
+		ret.Mul(ret, xi)
+		ret.Add(ret, p[i])
+		ret.Mod(ret, n)
+	}
+	return ret
+}
+
+// lagrange returns the coefficient of the share of id when interpolating
+// the value at 0 from the shares of ids.
+func lagrange(id uint32, ids []uint32, n *big.Int) *big.Int {
+	num, den := big.NewInt(1), big.NewInt(1)
+	xi := new(big.Int).SetUint64(uint64(id))
+	for _, j := range ids {
+		if j == id {
+			continue
+		}
+		xj := new(big.Int).SetUint64(uint64(j))
+		num.Mul(num, xj)
+		num.Mod(num, n)
+		den.Mul(den, new(big.Int).Sub(xj, xi))
+		den.Mod(den, n)
+	}
+	return num.Mul(num, den.ModInverse(den, n)).Mod(num, n)
+}
+
+// interpolate returns the value at 0 of the polynomial through the shares.
+func interpolate(shares map[uint32]*big.Int, n *big.Int) *big.Int {
+	ids := make([]uint32, 0, len(shares))
+	for id := range shares {
+		ids = append(ids, id)
+	}
+	ret := new(big.Int)
+	for id, share := range shares {
+		ret.Add(ret, new(big.Int).Mul(share, lagrange(id, ids, n)))
+	}
+	return ret.Mod(ret, n)
+}
+
+func scalarBytes(k *big.Int, curve elliptic.Curve) []byte {
+	ret := make([]byte, (curve.Params().N.BitLen()+7)/8)
+	b := k.Bytes()
+	copy(ret[len(ret)-len(b):], b)
+	return ret
+}
+
+func checkParties(parties []uint32, id uint32) error {
+	seen := make(map[uint32]bool)
+	for _, p := range parties {
+		if p == 0 {
+			return fmt.Errorf("party id must not be 0")
+		}
+		if seen[p] {
+			return fmt.Errorf("duplicate party %d", p)
+		}
+		seen[p] = true
+	}
+	if !seen[id] {
+		return fmt.Errorf("party %d is not in the committee", id)
+	}
+	return nil
+}
+
+func sortedCopy(ids []uint32) []uint32 {
+	ret := append([]uint32{}, ids...)
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// round runs the message exchange of a protocol session for one party.
+type round struct {
+	ctx       context.Context
+	transport Transport
+	session   string
+	id        uint32
+	peers     []uint32
+	pending   []*Message
+}
+
+// exchange sends the payload for every peer and collects one message of the
+// round from each of them. Messages of later rounds are kept for later.
+func (r *round) exchange(num int, payloads map[uint32]interface{}) (map[uint32][]byte, error) {
+	for _, peer := range r.peers {
+		data, err := json.Marshal(payloads[peer])
+		if err != nil {
+			return nil, err
+		}
+		msg := &Message{Session: r.session, Round: num, From: r.id, Payload: data}
+		if err := r.transport.Send(r.ctx, peer, msg); err != nil {
+			return nil, fmt.Errorf("send round %d to %d: %w", num, peer, err)
+		}
+	}
+
+	isPeer := make(map[uint32]bool)
+	for _, peer := range r.peers {
+		isPeer[peer] = true
+	}
+	received := make(map[uint32][]byte)
+	var pending []*Message
+	for _, msg := range r.pending {
+		if msg.Round == num {
+			received[msg.From] = msg.Payload
+		} else {
+			pending = append(pending, msg)
+		}
+	}
+	r.pending = pending
+
+	for len(received) < len(r.peers) {
+		msg, err := r.transport.Receive(r.ctx, r.session)
+		if err != nil {
+			return nil, fmt.Errorf("receive round %d: %w", num, err)
+		}
+		if !isPeer[msg.From] {
+			return nil, abort(msg.From, "unexpected message")
+		}
+		switch {
+		case msg.Round < num:
+			return nil, abort(msg.From, "late message of round %d", msg.Round)
+		case msg.Round > num:
+			r.pending = append(r.pending, msg)
+		default:
+			if _, ok := received[msg.From]; ok {
+				return nil, abort(msg.From, "duplicate message of round %d", num)
+			}
+			received[msg.From] = msg.Payload
+		}
+	}
+	return received, nil
+}
+
+func broadcast(peers []uint32, payload interface{}) map[uint32]interface{} {
+	ret := make(map[uint32]interface{}, len(peers))
+	for _, peer := range peers {
+		ret[peer] = payload
+	}
+	return ret
+}
+
+func newRound(ctx context.Context, transport Transport, session string, id uint32, parties []uint32) *round {
+	var peers []uint32
+	for _, p := range parties {
+		if p != id {
+			peers = append(peers, p)
+		}
+	}
+	return &round{ctx: ctx, transport: transport, session: session, id: id, peers: peers}
+}
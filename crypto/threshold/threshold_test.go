@@ -0,0 +1,283 @@
+package threshold
+
+import (
+	"context"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym"
+	"github.com/stretchr/testify/require"
+)
+
+func keyGen(t *testing.T, network *MemoryNetwork, typ crypto.KeyType, threshold int, parties []uint32) map[uint32]*KeyShare {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var (
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+		shares = make(map[uint32]*KeyShare)
+	)
+	for _, id := range parties {
+		wg.Add(1)
+		go func(id uint32) {
+			defer wg.Done()
+			cfg := Config{Curve: typ, Threshold: threshold, Parties: parties, ID: id}
+			share, err := KeyGen(ctx, cfg, "keygen", network.Transport(id))
+			require.Nil(t, err)
+			lock.Lock()
+			shares[id] = share
+			lock.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return shares
+}
+
+func sign(t *testing.T, network *MemoryNetwork, shares map[uint32]*KeyShare, signers []uint32, session string, digest []byte) map[uint32][]byte {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		sigs = make(map[uint32][]byte)
+	)
+	for _, id := range signers {
+		wg.Add(1)
+		go func(id uint32) {
+			defer wg.Done()
+			sig, err := Sign(ctx, shares[id], signers, session, digest, network.Transport(id))
+			require.Nil(t, err)
+			lock.Lock()
+			sigs[id] = sig
+			lock.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return sigs
+}
+
+func TestThresholdSign(t *testing.T) {
+	parties := []uint32{1, 2, 3, 4, 5}
+	digest := sha256.Sum256([]byte("hyperchain"))
+
+	for _, typ := range []crypto.KeyType{crypto.Secp256k1, crypto.ECDSA_P256} {
+		network := NewMemoryNetwork(parties)
+		shares := keyGen(t, network, typ, 2, parties)
+		require.Equal(t, len(parties), len(shares))
+
+		pub, err := shares[1].PubKey()
+		require.Nil(t, err)
+		addr, err := pub.Address()
+		require.Nil(t, err)
+
+		// Any t+1 shares reconstruct the key, which is never done outside tests
+		curve, err := curveOf(typ)
+		require.Nil(t, err)
+		secret := interpolate(map[uint32]*big.Int{2: shares[2].Share, 4: shares[4].Share, 5: shares[5].Share}, curve.Params().N)
+		x, y := curve.ScalarBaseMult(scalarBytes(secret, curve))
+		require.Equal(t, shares[3].PublicKey, elliptic.Marshal(curve, x, y))
+
+		for i, signers := range [][]uint32{parties, {1, 3, 5, 4, 2}, {5, 4, 3, 2, 1}} {
+			sigs := sign(t, network, shares, signers, fmt.Sprintf("sign-%d", i), digest[:])
+			for _, sig := range sigs {
+				ok, err := pub.Verify(digest[:], sig)
+				require.Nil(t, err)
+				require.True(t, ok)
+
+				ok, err = asym.Verify(typ, sig, digest[:], *addr)
+				require.Nil(t, err)
+				require.True(t, ok)
+			}
+		}
+	}
+}
+
+func TestThresholdSignSubset(t *testing.T) {
+	parties := []uint32{1, 2, 3, 4}
+	digest := sha256.Sum256([]byte("hyperchain"))
+
+	network := NewMemoryNetwork(parties)
+	shares := keyGen(t, network, crypto.ECDSA_P256, 1, parties)
+	pub, err := shares[1].PubKey()
+	require.Nil(t, err)
+
+	sigs := sign(t, network, shares, []uint32{2, 3, 4}, "sign", digest[:])
+	require.Equal(t, 3, len(sigs))
+	for _, sig := range sigs {
+		ok, err := pub.Verify(digest[:], sig)
+		require.Nil(t, err)
+		require.True(t, ok)
+	}
+
+	_, err = Sign(context.Background(), shares[1], []uint32{1, 2}, "short", digest[:], network.Transport(1))
+	require.NotNil(t, err)
+	_, err = Sign(context.Background(), shares[1], []uint32{1, 2, 7}, "unknown", digest[:], network.Transport(1))
+	require.NotNil(t, err)
+}
+
+func TestKeyGenConfig(t *testing.T) {
+	parties := []uint32{1, 2, 3}
+	network := NewMemoryNetwork(parties)
+	ctx := context.Background()
+
+	_, err := KeyGen(ctx, Config{Curve: crypto.ECDSA_P256, Threshold: 2, Parties: parties, ID: 1}, "a", network.Transport(1))
+	require.NotNil(t, err)
+	_, err = KeyGen(ctx, Config{Curve: crypto.Ed25519, Threshold: 1, Parties: parties, ID: 1}, "b", network.Transport(1))
+	require.NotNil(t, err)
+	_, err = KeyGen(ctx, Config{Curve: crypto.ECDSA_P256, Threshold: 1, Parties: []uint32{1, 1, 2}, ID: 1}, "c", network.Transport(1))
+	require.NotNil(t, err)
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = KeyGen(timeout, Config{Curve: crypto.ECDSA_P256, Threshold: 1, Parties: parties, ID: 1}, "d", network.Transport(1))
+	require.NotNil(t, err)
+}
+
+// tamperTransport lets a party deviate from the protocol by changing the
+// messages it sends
+type tamperTransport struct {
+	Transport
+	tamper func(to uint32, msg *Message) *Message
+}
+
+func (t *tamperTransport) Send(ctx context.Context, to uint32, msg *Message) error {
+	return t.Transport.Send(ctx, to, t.tamper(to, msg))
+}
+
+func runParties(parties []uint32, run func(ctx context.Context, id uint32) error) map[uint32]error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs = make(map[uint32]error)
+	)
+	for _, id := range parties {
+		wg.Add(1)
+		go func(id uint32) {
+			defer wg.Done()
+			err := run(ctx, id)
+			lock.Lock()
+			errs[id] = err
+			lock.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return errs
+}
+
+func requireAbort(t *testing.T, err error, parties ...uint32) {
+	var abortErr *AbortError
+	require.True(t, errors.As(err, &abortErr), "%v", err)
+	require.Equal(t, parties, abortErr.Parties)
+}
+
+func TestKeyGenAbort(t *testing.T) {
+	parties := []uint32{1, 2, 3, 4, 5}
+	cfg := Config{Curve: crypto.ECDSA_P256, Threshold: 2, Parties: parties}
+	curve := elliptic.P256()
+
+	keyGen := func(tamper func(to uint32, msg *Message) *Message) map[uint32]error {
+		network := NewMemoryNetwork(parties)
+		return runParties(parties, func(ctx context.Context, id uint32) error {
+			transport := network.Transport(id)
+			if id == 3 {
+				transport = &tamperTransport{Transport: transport, tamper: tamper}
+			}
+			cfg := cfg
+			cfg.ID = id
+			_, err := KeyGen(ctx, cfg, "keygen", transport)
+			return err
+		})
+	}
+
+	// A share which doesn't match the commitments
+	errs := keyGen(func(to uint32, msg *Message) *Message {
+		if msg.Round == 1 && to == 1 {
+			deal := &dealMsg{}
+			require.Nil(t, json.Unmarshal(msg.Payload, deal))
+			deal.Shares[0].Add(deal.Shares[0], big.NewInt(1))
+			msg.Payload, _ = json.Marshal(deal)
+		}
+		return msg
+	})
+	requireAbort(t, errs[1], 3)
+
+	// A valid deal of another polynomial to party 1 is found by the echo
+	errs = keyGen(func(to uint32, msg *Message) *Message {
+		if msg.Round == 1 && to == 1 {
+			secret, err := randomScalar(curve.Params().N)
+			require.Nil(t, err)
+			poly, err := newPolynomial(secret, cfg.Threshold, curve.Params().N)
+			require.Nil(t, err)
+			deal := &dealMsg{
+				Commitments: [][][]byte{marshalPoints(curve, commit(curve, poly))},
+				Shares:      []*big.Int{poly.eval(to, curve.Params().N)},
+			}
+			msg.Payload, _ = json.Marshal(deal)
+		}
+		return msg
+	})
+	// Party 1 hears the other commitments from everyone, the others only
+	// from party 1 and can't tell whether it or the dealer lies
+	requireAbort(t, errs[1], 3)
+	for _, id := range []uint32{2, 4, 5} {
+		requireAbort(t, errs[id], 1, 3)
+	}
+
+	// An echo lying about the commitments of the honest party 2
+	errs = keyGen(func(to uint32, msg *Message) *Message {
+		if msg.Round == 2 {
+			echo := &echoMsg{}
+			require.Nil(t, json.Unmarshal(msg.Payload, echo))
+			echo.Hashes[2] = commitmentsHash(nil)
+			msg.Payload, _ = json.Marshal(echo)
+		}
+		return msg
+	})
+	requireAbort(t, errs[2], 3)
+	for _, id := range []uint32{1, 4, 5} {
+		requireAbort(t, errs[id], 2, 3)
+	}
+}
+
+func TestSignAbort(t *testing.T) {
+	parties := []uint32{1, 2, 3, 4, 5}
+	digest := sha256.Sum256([]byte("hyperchain"))
+	network := NewMemoryNetwork(parties)
+	shares := keyGen(t, network, crypto.Secp256k1, 2, parties)
+
+	for _, round := range []int{3, 4} {
+		session := fmt.Sprintf("sign-%d", round)
+		errs := runParties(parties, func(ctx context.Context, id uint32) error {
+			transport := network.Transport(id)
+			if id == 3 {
+				transport = &tamperTransport{Transport: transport, tamper: func(to uint32, msg *Message) *Message {
+					if msg.Round == round {
+						open := &openMsg{}
+						require.Nil(t, json.Unmarshal(msg.Payload, open))
+						open.Share.Add(open.Share, big.NewInt(1))
+						msg.Payload, _ = json.Marshal(open)
+					}
+					return msg
+				}}
+			}
+			_, err := Sign(ctx, shares[id], parties, session, digest[:], transport)
+			return err
+		})
+		for _, id := range []uint32{1, 2, 4, 5} {
+			requireAbort(t, errs[id], 3)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package threshold
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// memoryQueueSize is the number of undelivered messages a session of the
+// in-memory transport holds before Send blocks.
+const memoryQueueSize = 256
+
+// Message is a protocol message from one party to another.
+type Message struct {
+	Session string `json:"session"`
+	Round   int    `json:"round"`
+	From    uint32 `json:"from"`
+	Payload []byte `json:"payload"`
+}
+
+// Transport carries the messages between the parties of a committee. It has
+// to deliver messages reliably, authenticate their senders and keep them
+// confidential, since they carry secret shares.
+type Transport interface {
+	// Send delivers msg to the party with the given id.
+	Send(ctx context.Context, to uint32, msg *Message) error
+
+	// Receive blocks until a message of the given session arrives.
+	Receive(ctx context.Context, session string) (*Message, error)
+}
+
+// MemoryNetwork connects parties in the same process, mostly for tests.
+type MemoryNetwork struct {
+	transports map[uint32]*memoryTransport
+}
+
+// NewMemoryNetwork creates a network of parties with the given ids.
+func NewMemoryNetwork(ids []uint32) *MemoryNetwork {
+	network := &MemoryNetwork{transports: make(map[uint32]*memoryTransport)}
+	for _, id := range ids {
+		network.transports[id] = &memoryTransport{
+			network: network,
+			queues:  make(map[string]chan *Message),
+		}
+	}
+	return network
+}
+
+// Transport returns the transport of the party with the given id.
+func (n *MemoryNetwork) Transport(id uint32) Transport {
+	t, ok := n.transports[id]
+	if !ok {
+		return nil
+	}
+	return t
+}
+
+type memoryTransport struct {
+	network *MemoryNetwork
+	lock    sync.Mutex
+	queues  map[string]chan *Message
+}
+
+func (t *memoryTransport) queue(session string) chan *Message {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	q, ok := t.queues[session]
+	if !ok {
+		q = make(chan *Message, memoryQueueSize)
+		t.queues[session] = q
+	}
+	return q
+}
+
+func (t *memoryTransport) Send(ctx context.Context, to uint32, msg *Message) error {
+	peer, ok := t.network.transports[to]
+	if !ok {
+		return fmt.Errorf("unknown party %d", to)
+	}
+
+	select {
+	case peer.queue(msg.Session) <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *memoryTransport) Receive(ctx context.Context, session string) (*Message, error) {
+	select {
+	case msg := <-t.queue(session):
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
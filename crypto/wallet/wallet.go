@@ -0,0 +1,377 @@
+// Package wallet manages a directory of password protected keystores indexed
+// by address. Unlocked keys are kept in memory for signing until they are
+// locked again, either explicitly or after a timeout.
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/ecdsa"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/ed25519"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/rsa"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/sm2"
+	"github.com/meshplus/bitxhub-kit/types"
+)
+
+var (
+	// ErrNotFound is returned for addresses without a keystore in the wallet
+	ErrNotFound = errors.New("no key for this address in the wallet")
+
+	// ErrExists is returned when importing a key the wallet already holds
+	ErrExists = errors.New("key already exists in the wallet")
+
+	// ErrLocked is returned when signing with a key which is not unlocked
+	ErrLocked = errors.New("key is locked")
+)
+
+const keyFileExt = ".json"
+
+// Wallet is a directory of keystores, one file per address.
+type Wallet struct {
+	dir  string
+	opts []asym.KeyStoreOption
+
+	files    sync.Mutex // Serializes changes of the keystore files
+	lock     sync.Mutex
+	unlocked map[[types.AddressLength]byte]*unlockedKey
+}
+
+type unlockedKey struct {
+	priv  crypto.PrivateKey
+	timer *time.Timer
+}
+
+// New opens the wallet in dir, creating the directory if needed. The options
+// are used for every keystore the wallet writes.
+func New(dir string, opts ...asym.KeyStoreOption) (*Wallet, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &Wallet{
+		dir:      dir,
+		opts:     opts,
+		unlocked: make(map[[types.AddressLength]byte]*unlockedKey),
+	}, nil
+}
+
+// List returns the addresses of all keys in the wallet in ascending order.
+func (w *Wallet) List() ([]types.Address, error) {
+	files, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []types.Address
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, keyFileExt) {
+			continue
+		}
+		raw, err := hex.DecodeString(strings.TrimSuffix(name, keyFileExt))
+		if err != nil || len(raw) != types.AddressLength {
+			continue
+		}
+		addrs = append(addrs, *types.NewAddress(raw))
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i].RawAddress[:], addrs[j].RawAddress[:]) < 0
+	})
+
+	return addrs, nil
+}
+
+// Has reports whether the wallet holds a key for the address.
+func (w *Wallet) Has(addr types.Address) bool {
+	_, err := os.Stat(w.keyFile(addr))
+	return err == nil
+}
+
+// Import encrypts the private key with the password and adds it to the wallet.
+func (w *Wallet) Import(priv crypto.PrivateKey, password string) (*types.Address, error) {
+	addr, err := priv.PublicKey().Address()
+	if err != nil {
+		return nil, err
+	}
+
+	w.files.Lock()
+	defer w.files.Unlock()
+
+	if w.Has(*addr) {
+		return nil, ErrExists
+	}
+
+	if err := w.store(*addr, priv, password); err != nil {
+		return nil, err
+	}
+
+	return addr, nil
+}
+
+// ImportKeyStore adds the key of an encoded keystore to the wallet, encrypted
+// with newPassword.
+func (w *Wallet) ImportKeyStore(data []byte, password, newPassword string) (*types.Address, error) {
+	keyStore := &crypto.KeyStore{}
+	if err := json.Unmarshal(data, keyStore); err != nil {
+		return nil, err
+	}
+	priv, err := asym.DecryptKeyStore(keyStore, password)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(priv)
+
+	return w.Import(priv, newPassword)
+}
+
+// Export returns the key of the address as an encoded keystore encrypted with
+// newPassword.
+func (w *Wallet) Export(addr types.Address, password, newPassword string) ([]byte, error) {
+	priv, err := w.decrypt(addr, password)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(priv)
+
+	keyStore, err := asym.GenKeyStore(priv, newPassword, w.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(keyStore, "", " ")
+}
+
+// Delete locks the key and removes it from the wallet, the password must be
+// correct.
+func (w *Wallet) Delete(addr types.Address, password string) error {
+	w.files.Lock()
+	defer w.files.Unlock()
+
+	priv, err := w.decrypt(addr, password)
+	if err != nil {
+		return err
+	}
+	zeroKey(priv)
+
+	w.Lock(addr)
+
+	return os.Remove(w.keyFile(addr))
+}
+
+// ChangePassword encrypts the key of the address with a new password.
+func (w *Wallet) ChangePassword(addr types.Address, password, newPassword string) error {
+	w.files.Lock()
+	defer w.files.Unlock()
+
+	priv, err := w.decrypt(addr, password)
+	if err != nil {
+		return err
+	}
+	defer zeroKey(priv)
+
+	return w.store(addr, priv, newPassword)
+}
+
+// Unlock decrypts the key of the address and keeps it in memory. It is locked
+// again after timeout, or only by Lock if timeout is zero. Unlocking an
+// unlocked key replaces its timeout.
+func (w *Wallet) Unlock(addr types.Address, password string, timeout time.Duration) error {
+	priv, err := w.decrypt(addr, password)
+	if err != nil {
+		return err
+	}
+
+	key := &unlockedKey{priv: priv}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.lockLocked(addr.RawAddress)
+	if timeout > 0 {
+		key.timer = time.AfterFunc(timeout, func() {
+			w.lock.Lock()
+			defer w.lock.Unlock()
+
+			// The key may have been locked or unlocked again meanwhile
+			if w.unlocked[addr.RawAddress] == key {
+				w.lockLocked(addr.RawAddress)
+			}
+		})
+	}
+	w.unlocked[addr.RawAddress] = key
+
+	return nil
+}
+
+// Lock removes the key of the address from memory and zeroes it.
+func (w *Wallet) Lock(addr types.Address) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.lockLocked(addr.RawAddress)
+}
+
+// LockAll locks every unlocked key.
+func (w *Wallet) LockAll() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for raw := range w.unlocked {
+		w.lockLocked(raw)
+	}
+}
+
+// IsUnlocked reports whether the key of the address is unlocked.
+func (w *Wallet) IsUnlocked(addr types.Address) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	_, ok := w.unlocked[addr.RawAddress]
+	return ok
+}
+
+// Sign signs digest with the unlocked key of the address.
+func (w *Wallet) Sign(addr types.Address, digest []byte) ([]byte, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	key, ok := w.unlocked[addr.RawAddress]
+	if !ok {
+		return nil, ErrLocked
+	}
+
+	return key.priv.Sign(digest)
+}
+
+// Signer returns a crypto.Signer of the unlocked key of the address. It fails
+// with ErrLocked once the key is locked.
+func (w *Wallet) Signer(addr types.Address) (crypto.Signer, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	key, ok := w.unlocked[addr.RawAddress]
+	if !ok {
+		return nil, ErrLocked
+	}
+
+	return &walletSigner{wallet: w, addr: addr, pub: key.priv.PublicKey()}, nil
+}
+
+type walletSigner struct {
+	wallet *Wallet
+	addr   types.Address
+	pub    crypto.PublicKey
+}
+
+func (s *walletSigner) Sign(ctx context.Context, digest []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.wallet.Sign(s.addr, digest)
+}
+
+func (s *walletSigner) PublicKey() crypto.PublicKey {
+	return s.pub
+}
+
+// lockLocked locks the key of the address, w.lock must be held
+func (w *Wallet) lockLocked(raw [types.AddressLength]byte) {
+	key, ok := w.unlocked[raw]
+	if !ok {
+		return
+	}
+	if key.timer != nil {
+		key.timer.Stop()
+	}
+	zeroKey(key.priv)
+	delete(w.unlocked, raw)
+}
+
+func (w *Wallet) keyFile(addr types.Address) string {
+	return filepath.Join(w.dir, hex.EncodeToString(addr.RawAddress[:])+keyFileExt)
+}
+
+func (w *Wallet) decrypt(addr types.Address, password string) (crypto.PrivateKey, error) {
+	data, err := ioutil.ReadFile(w.keyFile(addr))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	keyStore := &crypto.KeyStore{}
+	if err := json.Unmarshal(data, keyStore); err != nil {
+		return nil, err
+	}
+
+	priv, err := asym.DecryptKeyStore(keyStore, password)
+	if err != nil {
+		return nil, err
+	}
+	privAddr, err := priv.PublicKey().Address()
+	if err != nil {
+		return nil, err
+	}
+	if privAddr.RawAddress != addr.RawAddress {
+		zeroKey(priv)
+		return nil, fmt.Errorf("keystore of %s holds the key of %s", addr.String(), privAddr.String())
+	}
+
+	return priv, nil
+}
+
+func (w *Wallet) store(addr types.Address, priv crypto.PrivateKey, password string) error {
+	keyStore, err := asym.GenKeyStore(priv, password, w.opts...)
+	if err != nil {
+		return err
+	}
+
+	return asym.StoreKeyStore(keyStore, w.keyFile(addr))
+}
+
+// zeroKey overwrites the secret of the private key. The secrets of BLS and
+// Schnorr keys are unexported and left to the garbage collector.
+func zeroKey(priv crypto.PrivateKey) {
+	switch key := priv.(type) {
+	case *ecdsa.PrivateKey:
+		zeroBig(key.K.D)
+	case *sm2.PrivateKey:
+		zeroBig(key.K.D)
+	case *ed25519.PrivateKey:
+		ecdsa.ZeroBytes(key.K)
+	case *rsa.PrivateKey:
+		zeroBig(key.K.D)
+		for _, prime := range key.K.Primes {
+			zeroBig(prime)
+		}
+		zeroBig(key.K.Precomputed.Dp)
+		zeroBig(key.K.Precomputed.Dq)
+		zeroBig(key.K.Precomputed.Qinv)
+	}
+}
+
+func zeroBig(b *big.Int) {
+	if b == nil {
+		return
+	}
+	words := b.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+	b.SetInt64(0)
+}
@@ -0,0 +1,149 @@
+package wallet
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/meshplus/bitxhub-kit/crypto"
+	"github.com/meshplus/bitxhub-kit/crypto/asym"
+	"github.com/meshplus/bitxhub-kit/crypto/asym/ecdsa"
+	"github.com/meshplus/bitxhub-kit/types"
+	"github.com/stretchr/testify/require"
+)
+
+func newWallet(t *testing.T) *Wallet {
+	dir, err := ioutil.TempDir("", "wallet")
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	w, err := New(dir, asym.WithLightScrypt())
+	require.Nil(t, err)
+	return w
+}
+
+func TestWallet(t *testing.T) {
+	w := newWallet(t)
+
+	var addrs []types.Address
+	for _, opt := range []crypto.KeyType{crypto.Secp256k1, crypto.Ed25519, crypto.SM2} {
+		priv, err := asym.GenerateKeyPair(opt)
+		require.Nil(t, err)
+		addr, err := w.Import(priv, "password")
+		require.Nil(t, err)
+		addrs = append(addrs, *addr)
+
+		_, err = w.Import(priv, "password")
+		require.Equal(t, ErrExists, err)
+	}
+
+	list, err := w.List()
+	require.Nil(t, err)
+	require.Equal(t, len(addrs), len(list))
+	for _, addr := range addrs {
+		require.True(t, w.Has(addr))
+	}
+
+	digest := sha256.Sum256([]byte("bitxhub"))
+	for _, addr := range addrs {
+		_, err := w.Sign(addr, digest[:])
+		require.Equal(t, ErrLocked, err)
+		require.NotNil(t, w.Unlock(addr, "wrong", 0))
+
+		require.Nil(t, w.Unlock(addr, "password", 0))
+		signer, err := w.Signer(addr)
+		require.Nil(t, err)
+		sig, err := asym.SignWithSigner(context.Background(), signer, digest[:])
+		require.Nil(t, err)
+		ok, err := asym.VerifyWithType(sig, digest[:], addr)
+		require.Nil(t, err)
+		require.True(t, ok)
+	}
+
+	w.LockAll()
+	for _, addr := range addrs {
+		require.False(t, w.IsUnlocked(addr))
+	}
+
+	require.Nil(t, w.ChangePassword(addrs[0], "password", "new"))
+	require.NotNil(t, w.Unlock(addrs[0], "password", 0))
+	require.Nil(t, w.Unlock(addrs[0], "new", 0))
+
+	data, err := w.Export(addrs[0], "new", "export")
+	require.Nil(t, err)
+	require.NotNil(t, w.Delete(addrs[0], "wrong"))
+	require.Nil(t, w.Delete(addrs[0], "new"))
+	require.False(t, w.Has(addrs[0]))
+	require.False(t, w.IsUnlocked(addrs[0]))
+	require.Equal(t, ErrNotFound, w.Unlock(addrs[0], "new", 0))
+
+	other := newWallet(t)
+	addr, err := other.ImportKeyStore(data, "export", "other")
+	require.Nil(t, err)
+	require.Equal(t, addrs[0].RawAddress, addr.RawAddress)
+	require.Nil(t, other.Unlock(*addr, "other", 0))
+}
+
+func TestWalletUnlockTimeout(t *testing.T) {
+	w := newWallet(t)
+	priv, err := asym.GenerateKeyPair(crypto.Secp256k1)
+	require.Nil(t, err)
+	addr, err := w.Import(priv, "password")
+	require.Nil(t, err)
+
+	require.Nil(t, w.Unlock(*addr, "password", 50*time.Millisecond))
+	w.lock.Lock()
+	unlocked := w.unlocked[addr.RawAddress].priv.(*ecdsa.PrivateKey)
+	w.lock.Unlock()
+	require.NotZero(t, unlocked.K.D.Sign())
+
+	require.Eventually(t, func() bool {
+		return !w.IsUnlocked(*addr)
+	}, time.Second, 10*time.Millisecond)
+	require.Zero(t, unlocked.K.D.Sign())
+
+	// Unlocking again replaces the timeout
+	require.Nil(t, w.Unlock(*addr, "password", 50*time.Millisecond))
+	require.Nil(t, w.Unlock(*addr, "password", 0))
+	time.Sleep(100 * time.Millisecond)
+	require.True(t, w.IsUnlocked(*addr))
+}
+
+func TestWalletConcurrentImport(t *testing.T) {
+	w := newWallet(t)
+	priv, err := asym.GenerateKeyPair(crypto.Ed25519)
+	require.Nil(t, err)
+
+	// Only one of the imports of the same key may succeed
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, 4)
+	)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = w.Import(priv, fmt.Sprintf("password%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	imported := -1
+	for i, err := range errs {
+		if err == nil {
+			require.Equal(t, -1, imported)
+			imported = i
+			continue
+		}
+		require.Equal(t, ErrExists, err)
+	}
+	require.NotEqual(t, -1, imported)
+	addr, err := priv.PublicKey().Address()
+	require.Nil(t, err)
+	require.Nil(t, w.Unlock(*addr, fmt.Sprintf("password%d", imported), 0))
+}
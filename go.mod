@@ -3,19 +3,23 @@ module github.com/meshplus/bitxhub-kit
 go 1.13
 
 require (
+	filippo.io/edwards25519 v1.0.0
 	github.com/btcsuite/btcd v0.21.0-beta
 	github.com/cbergoon/merkletree v0.2.0
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1
 	github.com/fastly/go-utils v0.0.0-20180712184237-d95a45783239 // indirect
 	github.com/go-kit/kit v0.10.0 // indirect
 	github.com/gogo/protobuf v1.3.2
 	github.com/golang/protobuf v1.5.2
 	github.com/jehiah/go-strftime v0.0.0-20171201141054-1d33003b3869 // indirect
+	github.com/kilic/bls12-381 v0.1.0
 	github.com/lestrrat-go/file-rotatelogs v2.2.0+incompatible
 	github.com/lestrrat-go/strftime v1.0.0 // indirect
 	github.com/libp2p/go-libp2p v0.5.0
 	github.com/libp2p/go-libp2p-core v0.3.0
 	github.com/mattn/go-colorable v0.1.4 // indirect
 	github.com/mattn/go-isatty v0.0.10 // indirect
+	github.com/mr-tron/base58 v1.1.3
 	github.com/multiformats/go-multiaddr v0.2.0
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
 	github.com/prometheus/tsdb v0.10.0
@@ -27,5 +31,6 @@ require (
 	github.com/tebeka/strftime v0.1.3 // indirect
 	golang.org/x/crypto v0.0.0-20201117144127-c1f2f97bffc9
 	golang.org/x/sys v0.4.0 // indirect
+	golang.org/x/text v0.3.3
 	gopkg.in/check.v1 v1.0.0-20200902074654-038fdea0a05b // indirect
 )
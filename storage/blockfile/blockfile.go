@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/prometheus/tsdb/fileutil"
 	"github.com/sirupsen/logrus"
@@ -16,17 +17,31 @@ const (
 	storageRoot = "/storage/blockfile"
 )
 
+var errReadOnly = fmt.Errorf("block file is opened in read-only mode")
+
 type BlockFile struct {
-	blocks uint64 // Number of blocks
+	blocks   uint64 // Number of blocks
+	unsynced uint64 // Number of blocks appended since the last sync
 
 	tables       map[string]*BlockTable // Data tables for stroring blocks
+	hashIndex    *hashIndex             // Optional hash to number index
 	instanceLock fileutil.Releaser      // File-system lock to prevent double opens
+	conf         *config
+
+	appendLock sync.Mutex // Serializes appends and truncations
+	closeC     chan struct{}
+	wg         sync.WaitGroup
 
 	logger    logrus.FieldLogger
 	closeOnce sync.Once
 }
 
-func NewBlockFile(repoRoot string, logger logrus.FieldLogger) (*BlockFile, error) {
+func NewBlockFile(repoRoot string, logger logrus.FieldLogger, opts ...Option) (*BlockFile, error) {
+	conf, err := generateConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
 	if info, err := os.Lstat(repoRoot); !os.IsNotExist(err) {
 		if info.Mode()&os.ModeSymlink != 0 {
 			logger.WithField("path", repoRoot).Error("Symbolic link is not supported")
@@ -34,7 +49,10 @@ func NewBlockFile(repoRoot string, logger logrus.FieldLogger) (*BlockFile, error
 		}
 	}
 	blockFileRoot := repoRoot + storageRoot
-	err := os.MkdirAll(blockFileRoot, 0755)
+	if conf.readOnly {
+		return openReadOnly(blockFileRoot, conf, logger)
+	}
+	err = os.MkdirAll(blockFileRoot, 0755)
 	if err != nil {
 		return nil, err
 	}
@@ -45,30 +63,91 @@ func NewBlockFile(repoRoot string, logger logrus.FieldLogger) (*BlockFile, error
 	blockfile := &BlockFile{
 		tables:       make(map[string]*BlockTable),
 		instanceLock: lock,
+		conf:         conf,
+		closeC:       make(chan struct{}),
 		logger:       logger,
 	}
 	for name := range BlockFileSchema {
-		table, err := newTable(blockFileRoot, name, 2*1000*1000*1000, logger)
+		table, err := newTable(blockFileRoot, name, conf, logger)
 		if err != nil {
-			for _, table := range blockfile.tables {
-				table.Close()
-			}
+			blockfile.closeTables()
 			_ = lock.Release()
 			return nil, err
 		}
 		blockfile.tables[name] = table
 	}
 	if err := blockfile.repair(); err != nil {
-		for _, table := range blockfile.tables {
-			table.Close()
-		}
+		blockfile.closeTables()
 		_ = lock.Release()
 		return nil, err
 	}
+	if err := blockfile.openHashIndex(blockFileRoot); err != nil {
+		blockfile.closeTables()
+		_ = lock.Release()
+		return nil, err
+	}
+	if conf.syncPolicy == syncInterval {
+		blockfile.wg.Add(1)
+		go blockfile.syncLoop()
+	}
+
+	return blockfile, nil
+}
+
+// openReadOnly opens all tables as they are on disk. The block count is the
+// length of the shortest table, nothing gets truncated.
+func openReadOnly(blockFileRoot string, conf *config, logger logrus.FieldLogger) (*BlockFile, error) {
+	if _, err := os.Stat(blockFileRoot); err != nil {
+		return nil, err
+	}
+	blockfile := &BlockFile{
+		tables: make(map[string]*BlockTable),
+		conf:   conf,
+		closeC: make(chan struct{}),
+		logger: logger,
+	}
+	min := uint64(math.MaxUint64)
+	for name := range BlockFileSchema {
+		table, err := newTable(blockFileRoot, name, conf, logger)
+		if err != nil {
+			blockfile.closeTables()
+			return nil, err
+		}
+		blockfile.tables[name] = table
+		if items := atomic.LoadUint64(&table.items); min > items {
+			min = items
+		}
+	}
+	blockfile.blocks = min
+	if err := blockfile.openHashIndex(blockFileRoot); err != nil {
+		blockfile.closeTables()
+		return nil, err
+	}
 
 	return blockfile, nil
 }
 
+func (bf *BlockFile) openHashIndex(blockFileRoot string) error {
+	if !bf.conf.hashIndex {
+		return nil
+	}
+	idx, err := newHashIndex(blockFileRoot, bf.tables[BlockFileHashTable], bf.blocks, bf.conf.readOnly, bf.logger)
+	if err != nil {
+		return err
+	}
+	bf.hashIndex = idx
+	return nil
+}
+
+func (bf *BlockFile) closeTables() {
+	for _, table := range bf.tables {
+		table.Close()
+	}
+	if bf.hashIndex != nil {
+		bf.hashIndex.Close()
+	}
+}
+
 func (bf *BlockFile) Blocks() (uint64, error) {
 	return atomic.LoadUint64(&bf.blocks), nil
 }
@@ -80,79 +159,225 @@ func (bf *BlockFile) Get(kind string, number uint64) ([]byte, error) {
 	return nil, fmt.Errorf("unknown table")
 }
 
-func (bf *BlockFile) AppendBlock(number uint64, hash, body, receipts, transactions, interchainMetas []byte) (err error) {
+// NumberByHash returns the number of the block with the given hash. It
+// requires the hash index to be enabled by WithHashIndex.
+func (bf *BlockFile) NumberByHash(hash []byte) (uint64, error) {
+	if bf.hashIndex == nil {
+		return 0, fmt.Errorf("hash index is disabled")
+	}
+	item, err := bf.hashIndex.get(hash, bf.tables[BlockFileHashTable])
+	if err != nil {
+		return 0, err
+	}
+	return item + 1, nil
+}
+
+// GetByHash retrieves the data of the given kind for the block with the given
+// hash. It requires the hash index to be enabled by WithHashIndex.
+func (bf *BlockFile) GetByHash(kind string, hash []byte) ([]byte, error) {
+	number, err := bf.NumberByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	return bf.Get(kind, number)
+}
+
+// View calls fn with the content of the given item without copying it when
+// the item is memory mapped. See BlockTable.View for the lifetime of the slice.
+func (bf *BlockFile) View(kind string, number uint64, fn func(blob []byte) error) error {
+	if table := bf.tables[kind]; table != nil {
+		return table.View(number-1, fn)
+	}
+	return fmt.Errorf("unknown table")
+}
+
+// AppendBlock appends the data of a block to all tables in parallel. Either all
+// tables get the block or, if any write or the sync required by the sync
+// policy fails, all of them are truncated back to the previous block. The
+// block only counts once AppendBlock succeeded.
+func (bf *BlockFile) AppendBlock(number uint64, hash, body, receipts, transactions, interchainMetas []byte) error {
+	if bf.conf.readOnly {
+		return errReadOnly
+	}
+
+	bf.appendLock.Lock()
+	defer bf.appendLock.Unlock()
+
 	if atomic.LoadUint64(&bf.blocks) != number {
 		return fmt.Errorf("the append operation is out-order")
 	}
-	defer func() {
-		if err != nil {
-			rerr := bf.repair()
-			if rerr != nil {
-				bf.logger.WithField("err", err).Errorf("Failed to repair blockfile")
-			}
-			bf.logger.WithFields(logrus.Fields{
-				"number": number,
-				"err":    err,
-			}).Info("Append block failed")
-		}
-	}()
-	if err := bf.tables[BlockFileHashTable].Append(bf.blocks, hash); err != nil {
-		bf.logger.WithFields(logrus.Fields{
-			"number": bf.blocks,
-			"hash":   hash,
-			"err":    err,
-		}).Error("Failed to append block hash")
-		return err
+	items := map[string][]byte{
+		BlockFileHashTable:       hash,
+		BlockFileBodiesTable:     body,
+		BlockFileTXsTable:        transactions,
+		BlockFileReceiptTable:    receipts,
+		BlockFileInterchainTable: interchainMetas,
 	}
-	if err := bf.tables[BlockFileBodiesTable].Append(bf.blocks, body); err != nil {
-		bf.logger.WithFields(logrus.Fields{
-			"number": bf.blocks,
-			"hash":   hash,
-			"err":    err,
-		}).Error("Failed to append block body")
-		return err
+	err := bf.appendTables(number, items)
+	if err == nil && bf.hashIndex != nil {
+		err = bf.hashIndex.append(number, hash)
 	}
-	if err := bf.tables[BlockFileTXsTable].Append(bf.blocks, transactions); err != nil {
-		bf.logger.WithFields(logrus.Fields{
-			"number": bf.blocks,
-			"hash":   hash,
-			"err":    err,
-		}).Error("Failed to append block transactions")
-		return err
+	if err == nil {
+		err = bf.syncAppended()
 	}
-	if err := bf.tables[BlockFileReceiptTable].Append(bf.blocks, receipts); err != nil {
-		bf.logger.WithFields(logrus.Fields{
-			"number": bf.blocks,
-			"hash":   hash,
-			"err":    err,
-		}).Error("Failed to append block receipt")
-		return err
-	}
-	if err := bf.tables[BlockFileInterchainTable].Append(bf.blocks, interchainMetas); err != nil {
+	if err != nil {
+		if rerr := bf.rollback(number); rerr != nil {
+			bf.logger.WithField("err", rerr).Errorf("Failed to roll back blockfile")
+		}
 		bf.logger.WithFields(logrus.Fields{
-			"number": bf.blocks,
-			"hash":   hash,
+			"number": number,
 			"err":    err,
-		}).Error("Failed to append block interchain metas")
+		}).Info("Append block failed")
 		return err
 	}
 	atomic.AddUint64(&bf.blocks, 1) // Only modify atomically
 	return nil
 }
 
-func (bf *BlockFile) TruncateBlocks(items uint64) error {
-	if atomic.LoadUint64(&bf.blocks) <= items {
-		return nil
+// syncAppended applies the sync policy to a block which has been written but
+// not yet counted.
+func (bf *BlockFile) syncAppended() error {
+	switch bf.conf.syncPolicy {
+	case syncEveryBlock:
+		atomic.AddUint64(&bf.unsynced, 1)
+		return bf.sync()
+	case syncEveryN:
+		if atomic.AddUint64(&bf.unsynced, 1) >= bf.conf.syncBlocks {
+			return bf.sync()
+		}
+	case syncInterval:
+		atomic.AddUint64(&bf.unsynced, 1)
 	}
+	return nil
+}
+
+func (bf *BlockFile) appendTables(number uint64, items map[string][]byte) error {
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs []error
+	)
+	for name, blob := range items {
+		table := bf.tables[name]
+		if table == nil {
+			return fmt.Errorf("unknown table %s", name)
+		}
+		wg.Add(1)
+		go func(name string, table *BlockTable, blob []byte) {
+			defer wg.Done()
+			if err := table.Append(number, blob); err != nil {
+				bf.logger.WithFields(logrus.Fields{
+					"number": number,
+					"table":  name,
+					"err":    err,
+				}).Error("Failed to append block data")
+				lock.Lock()
+				errs = append(errs, fmt.Errorf("append to table %s: %w", name, err))
+				lock.Unlock()
+			}
+		}(name, table, blob)
+	}
+	wg.Wait()
+	if len(errs) != 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// rollback truncates all tables to the given number of blocks.
+func (bf *BlockFile) rollback(items uint64) error {
 	for _, table := range bf.tables {
 		if err := table.truncate(items); err != nil {
 			return err
 		}
 	}
+	if bf.hashIndex != nil {
+		if err := bf.hashIndex.truncate(items); err != nil {
+			return err
+		}
+	}
 	atomic.StoreUint64(&bf.blocks, items)
 	return nil
 }
 
+// Sync flushes the data of all tables to disk.
+func (bf *BlockFile) Sync() error {
+	return bf.sync()
+}
+
+func (bf *BlockFile) sync() error {
+	unsynced := atomic.SwapUint64(&bf.unsynced, 0)
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs []error
+	)
+	for _, table := range bf.tables {
+		wg.Add(1)
+		go func(table *BlockTable) {
+			defer wg.Done()
+			if err := table.Sync(); err != nil {
+				lock.Lock()
+				errs = append(errs, err)
+				lock.Unlock()
+			}
+		}(table)
+	}
+	wg.Wait()
+	if bf.hashIndex != nil {
+		if err := bf.hashIndex.sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		// The blocks are still to be synced
+		atomic.AddUint64(&bf.unsynced, unsynced)
+		return fmt.Errorf("sync block file: %v", errs)
+	}
+	return nil
+}
+
+func (bf *BlockFile) syncLoop() {
+	defer bf.wg.Done()
+
+	ticker := time.NewTicker(bf.conf.syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if atomic.LoadUint64(&bf.unsynced) == 0 {
+				continue
+			}
+			if err := bf.sync(); err != nil {
+				bf.logger.WithField("err", err).Error("Failed to sync blockfile")
+			}
+		case <-bf.closeC:
+			return
+		}
+	}
+}
+
+func (bf *BlockFile) TruncateBlocks(items uint64) error {
+	if bf.conf.readOnly {
+		return errReadOnly
+	}
+
+	bf.appendLock.Lock()
+	defer bf.appendLock.Unlock()
+
+	if atomic.LoadUint64(&bf.blocks) <= items {
+		return nil
+	}
+	if err := bf.rollback(items); err != nil {
+		return err
+	}
+	if bf.conf.syncPolicy != syncNone {
+		return bf.sync()
+	}
+	return nil
+}
+
 // repair truncates all data tables to the same length.
 func (bf *BlockFile) repair() error {
 	min := uint64(math.MaxUint64)
@@ -174,13 +399,27 @@ func (bf *BlockFile) repair() error {
 func (bf *BlockFile) Close() error {
 	var errs []error
 	bf.closeOnce.Do(func() {
+		close(bf.closeC)
+		bf.wg.Wait()
+		if bf.conf.syncPolicy != syncNone {
+			if err := bf.sync(); err != nil {
+				errs = append(errs, err)
+			}
+		}
 		for _, table := range bf.tables {
 			if err := table.Close(); err != nil {
 				errs = append(errs, err)
 			}
 		}
-		if err := bf.instanceLock.Release(); err != nil {
-			errs = append(errs, err)
+		if bf.hashIndex != nil {
+			if err := bf.hashIndex.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if bf.instanceLock != nil {
+			if err := bf.instanceLock.Release(); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	})
 	if errs != nil {
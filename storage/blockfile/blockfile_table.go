@@ -1,6 +1,8 @@
 package blockfile
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -28,47 +30,94 @@ type BlockTable struct {
 	headBytes  uint32 // Number of bytes written to the head file
 	itemOffset uint32 // Offset (number of discarded items)
 
+	readOnly bool // Whether the table is opened without repairing and writing
+
+	indexReader io.ReaderAt // Reads of the index, an in-memory copy for legacy read-only indexes
+	legacyIndex bool        // Whether the index file is a legacy one, only in read-only mode
+
+	mmap  bool              // Whether sealed data files are memory mapped
+	mmaps map[uint32][]byte // memory mapped sealed data files
+
 	logger logrus.FieldLogger
 	lock   sync.RWMutex // Mutex protecting the data file descriptors
 }
 
 type indexEntry struct {
-	filenum uint32 // stored as uint16 ( 2 bytes)
+	filenum uint32 // stored as uint32 ( 4 bytes)
 	offset  uint32 // stored as uint32 ( 4 bytes)
 }
 
-const indexEntrySize = 6
+const (
+	// indexMagic and indexVersion make up the header of a versioned index file.
+	indexMagic   = "bxti"
+	indexVersion = 2
+
+	indexHeaderSize = 8
+	indexEntrySize  = 8
+
+	// legacyIndexEntrySize is the entry size of the unversioned index format,
+	// which stores the file number as uint16.
+	legacyIndexEntrySize = 6
+)
 
 // unmarshallBinary deserializes binary b into the rawIndex entry.
 func (i *indexEntry) unmarshalBinary(b []byte) error {
-	i.filenum = uint32(binary.BigEndian.Uint16(b[:2]))
-	i.offset = binary.BigEndian.Uint32(b[2:6])
+	i.filenum = binary.BigEndian.Uint32(b[:4])
+	i.offset = binary.BigEndian.Uint32(b[4:8])
 	return nil
 }
 
 // marshallBinary serializes the rawIndex entry into binary.
 func (i *indexEntry) marshallBinary() []byte {
 	b := make([]byte, indexEntrySize)
-	binary.BigEndian.PutUint16(b[:2], uint16(i.filenum))
-	binary.BigEndian.PutUint32(b[2:6], i.offset)
+	binary.BigEndian.PutUint32(b[:4], i.filenum)
+	binary.BigEndian.PutUint32(b[4:8], i.offset)
+	return b
+}
+
+// unmarshalLegacyBinary deserializes a legacy 6-byte index entry.
+func (i *indexEntry) unmarshalLegacyBinary(b []byte) error {
+	i.filenum = uint32(binary.BigEndian.Uint16(b[:2]))
+	i.offset = binary.BigEndian.Uint32(b[2:6])
+	return nil
+}
+
+func indexHeader() []byte {
+	b := make([]byte, indexHeaderSize)
+	copy(b[:4], indexMagic)
+	binary.BigEndian.PutUint32(b[4:8], indexVersion)
 	return b
 }
 
-func newTable(path string, name string, maxFilesize uint32, logger logrus.FieldLogger) (*BlockTable, error) {
+// indexOffset returns the position of the n-th entry in the index file.
+func indexOffset(n uint64) int64 {
+	return indexHeaderSize + int64(n)*indexEntrySize
+}
+
+func newTable(path string, name string, conf *config, logger logrus.FieldLogger) (*BlockTable, error) {
+	idxName := filepath.Join(path, fmt.Sprintf("%s.ridx", name))
+	if conf.readOnly {
+		return openTableReadOnly(path, name, idxName, conf, logger)
+	}
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
 	}
-	idxName := fmt.Sprintf("%s.ridx", name)
-	offsets, err := openBlockFileForAppend(filepath.Join(path, idxName))
+	if err := migrateIndex(idxName, logger); err != nil {
+		return nil, err
+	}
+	offsets, err := openBlockFileForAppend(idxName)
 	if err != nil {
 		return nil, err
 	}
 	table := &BlockTable{
 		index:       offsets,
+		indexReader: offsets,
 		files:       make(map[uint32]*os.File),
+		mmaps:       make(map[uint32][]byte),
 		name:        name,
 		path:        path,
-		maxFileSize: maxFilesize,
+		maxFileSize: conf.maxFileSize,
+		mmap:        conf.mmap,
 		logger:      logger,
 	}
 	if err := table.repair(); err != nil {
@@ -77,6 +126,98 @@ func newTable(path string, name string, maxFilesize uint32, logger logrus.FieldL
 	return table, nil
 }
 
+func openTableReadOnly(path string, name string, idxName string, conf *config, logger logrus.FieldLogger) (*BlockTable, error) {
+	offsets, err := openBlockFileForReadOnly(idxName)
+	if err != nil {
+		return nil, err
+	}
+	table := &BlockTable{
+		index:       offsets,
+		files:       make(map[uint32]*os.File),
+		mmaps:       make(map[uint32][]byte),
+		name:        name,
+		path:        path,
+		maxFileSize: conf.maxFileSize,
+		readOnly:    true,
+		mmap:        conf.mmap,
+		logger:      logger,
+	}
+	if err := table.load(); err != nil {
+		table.Close()
+		return nil, err
+	}
+	return table, nil
+}
+
+// load initializes a read-only table from its index. Unlike repair it never
+// modifies the files, a partially written last index entry is just ignored
+// and a legacy index is converted in memory instead of being migrated.
+func (b *BlockTable) load() error {
+	legacy, err := isLegacyIndex(b.index)
+	if err != nil {
+		return err
+	}
+	var size int64
+	if legacy {
+		data, err := readLegacyIndex(b.index)
+		if err != nil {
+			return err
+		}
+		b.indexReader = bytes.NewReader(data)
+		b.legacyIndex = true
+		size = int64(len(data))
+	} else {
+		stat, err := b.index.Stat()
+		if err != nil {
+			return err
+		}
+		if stat.Size() < indexOffset(1) {
+			return fmt.Errorf("index file of table %s is empty", b.name)
+		}
+		if err := checkIndexHeader(b.index); err != nil {
+			return err
+		}
+		b.indexReader = b.index
+		size = stat.Size()
+	}
+	offsetsSize := size - (size-indexHeaderSize)%indexEntrySize
+	if offsetsSize < indexOffset(1) {
+		return fmt.Errorf("index file of table %s is empty", b.name)
+	}
+
+	var (
+		buffer     = make([]byte, indexEntrySize)
+		firstIndex indexEntry
+		lastIndex  indexEntry
+	)
+	if _, err := b.indexReader.ReadAt(buffer, indexOffset(0)); err != nil {
+		return err
+	}
+	if err := firstIndex.unmarshalBinary(buffer); err != nil {
+		return err
+	}
+	if _, err := b.indexReader.ReadAt(buffer, offsetsSize-indexEntrySize); err != nil {
+		return err
+	}
+	if err := lastIndex.unmarshalBinary(buffer); err != nil {
+		return err
+	}
+
+	b.tailId = firstIndex.filenum
+	b.itemOffset = firstIndex.offset
+	b.headId = lastIndex.filenum
+	b.headBytes = lastIndex.offset
+	b.items = uint64(b.itemOffset) + uint64((offsetsSize-indexHeaderSize)/indexEntrySize-1)
+
+	for i := b.tailId; i < b.headId; i++ {
+		if err := b.openSealedFile(i); err != nil {
+			return err
+		}
+	}
+	b.head, err = b.openFile(b.headId, openBlockFileForReadOnly)
+	return err
+}
+
 func (b *BlockTable) repair() error {
 	buffer := make([]byte, indexEntrySize)
 
@@ -85,11 +226,16 @@ func (b *BlockTable) repair() error {
 		return err
 	}
 	if stat.Size() == 0 {
-		if _, err := b.index.Write(buffer); err != nil {
+		if _, err := b.index.Write(indexHeader()); err != nil {
 			return err
 		}
+	} else if err := checkIndexHeader(b.index); err != nil {
+		return err
 	}
-	if remainder := stat.Size() % indexEntrySize; remainder != 0 {
+	if stat, err = b.index.Stat(); err != nil {
+		return err
+	}
+	if remainder := (stat.Size() - indexHeaderSize) % indexEntrySize; remainder != 0 {
 		err := truncateBlockFile(b.index, stat.Size()-remainder)
 		if err != nil {
 			return err
@@ -98,6 +244,14 @@ func (b *BlockTable) repair() error {
 	if stat, err = b.index.Stat(); err != nil {
 		return err
 	}
+	if stat.Size() == indexHeaderSize {
+		if _, err := b.index.Write(buffer); err != nil {
+			return err
+		}
+		if stat, err = b.index.Stat(); err != nil {
+			return err
+		}
+	}
 	offsetsSize := stat.Size()
 
 	// Open the head file
@@ -109,7 +263,7 @@ func (b *BlockTable) repair() error {
 	)
 	// Read index zero, determine what file is the earliest
 	// and what item offset to use
-	_, err = b.index.ReadAt(buffer, 0)
+	_, err = b.index.ReadAt(buffer, indexOffset(0))
 	if err != nil {
 		return err
 	}
@@ -192,7 +346,7 @@ func (b *BlockTable) repair() error {
 		return err
 	}
 	// Update the item and byte counters and return
-	b.items = uint64(b.itemOffset) + uint64(offsetsSize/indexEntrySize-1) // last indexEntry points to the end of the data file
+	b.items = uint64(b.itemOffset) + uint64((offsetsSize-indexHeaderSize)/indexEntrySize-1) // last indexEntry points to the end of the data file
 	b.headBytes = uint32(contentSize)
 	b.headId = lastIndex.filenum
 
@@ -212,6 +366,10 @@ func (b *BlockTable) truncate(items uint64) error {
 	b.lock.Lock()
 	defer b.lock.Unlock()
 
+	if b.readOnly {
+		return errReadOnly
+	}
+
 	existing := atomic.LoadUint64(&b.items)
 	if existing <= items {
 		return nil
@@ -221,12 +379,12 @@ func (b *BlockTable) truncate(items uint64) error {
 		"items": existing,
 		"limit": items,
 	}).Warn("Truncating block file")
-	if err := truncateBlockFile(b.index, int64(items+1)*indexEntrySize); err != nil {
+	if err := truncateBlockFile(b.index, indexOffset(items+1)); err != nil {
 		return err
 	}
 	// Calculate the new expected size of the data file and truncate it
 	buffer := make([]byte, indexEntrySize)
-	if _, err := b.index.ReadAt(buffer, int64(items*indexEntrySize)); err != nil {
+	if _, err := b.index.ReadAt(buffer, indexOffset(items)); err != nil {
 		return err
 	}
 	var expected indexEntry
@@ -262,36 +420,68 @@ func (b *BlockTable) truncate(items uint64) error {
 
 func (b *BlockTable) Retrieve(item uint64) ([]byte, error) {
 	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	startOffset, endOffset, filenum, err := b.locate(item)
+	if err != nil {
+		return nil, err
+	}
+	if data, exist := b.mmaps[filenum]; exist && int(endOffset) <= len(data) {
+		blob := make([]byte, endOffset-startOffset)
+		copy(blob, data[startOffset:endOffset])
+		return blob, nil
+	}
+	return b.readAt(filenum, startOffset, endOffset)
+}
+
+// View calls fn with the content of the given item. If the item lives in a
+// memory mapped data file, the slice handed to fn points directly into the
+// mapping and is only valid until fn returns, so it must be copied if fn needs
+// to keep it. The read lock of the table is held while fn runs, which keeps
+// the mapping from being released by a concurrent truncate, so fn must not call
+// back into the table.
+func (b *BlockTable) View(item uint64, fn func(blob []byte) error) error {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
 
+	startOffset, endOffset, filenum, err := b.locate(item)
+	if err != nil {
+		return err
+	}
+	if data, exist := b.mmaps[filenum]; exist && int(endOffset) <= len(data) {
+		return fn(data[startOffset:endOffset])
+	}
+	blob, err := b.readAt(filenum, startOffset, endOffset)
+	if err != nil {
+		return err
+	}
+	return fn(blob)
+}
+
+// locate returns the data file and the byte range of the given item. The
+// caller must hold the read lock.
+func (b *BlockTable) locate(item uint64) (uint32, uint32, uint32, error) {
 	if b.index == nil || b.head == nil {
-		b.lock.RUnlock()
-		return nil, fmt.Errorf("closed")
+		return 0, 0, 0, fmt.Errorf("closed")
 	}
 	if atomic.LoadUint64(&b.items) <= item {
-		b.lock.RUnlock()
-		return nil, fmt.Errorf("out of bounds")
+		return 0, 0, 0, fmt.Errorf("out of bounds")
 	}
 	if uint64(b.itemOffset) > item {
-		b.lock.RUnlock()
-		return nil, fmt.Errorf("out of bounds")
-	}
-	startOffset, endOffset, filenum, err := b.getBounds(item - uint64(b.itemOffset))
-	if err != nil {
-		b.lock.RUnlock()
-		return nil, err
+		return 0, 0, 0, fmt.Errorf("out of bounds")
 	}
+	return b.getBounds(item - uint64(b.itemOffset))
+}
+
+func (b *BlockTable) readAt(filenum, startOffset, endOffset uint32) ([]byte, error) {
 	dataFile, exist := b.files[filenum]
 	if !exist {
-		b.lock.RUnlock()
 		return nil, fmt.Errorf("missing data file %d", filenum)
 	}
 	blob := make([]byte, endOffset-startOffset)
 	if _, err := dataFile.ReadAt(blob, int64(startOffset)); err != nil {
-		b.lock.RUnlock()
 		return nil, err
 	}
-	b.lock.RUnlock()
-
 	return blob, nil
 }
 
@@ -301,6 +491,10 @@ func (b *BlockTable) Append(item uint64, blob []byte) error {
 		b.lock.RUnlock()
 		return fmt.Errorf("closed")
 	}
+	if b.readOnly {
+		b.lock.RUnlock()
+		return errReadOnly
+	}
 	if atomic.LoadUint64(&b.items) != item {
 		b.lock.RUnlock()
 		return fmt.Errorf("appending unexpected item: want %d, have %d", b.items, item)
@@ -318,10 +512,15 @@ func (b *BlockTable) Append(item uint64, blob []byte) error {
 			b.lock.Unlock()
 			return err
 		}
-		// Close old file, and reopen in RDONLY mode
+		// Flush the sealed file, close it, and reopen in RDONLY mode
+		if err := b.head.Sync(); err != nil {
+			b.lock.Unlock()
+			return err
+		}
 		b.releaseFile(b.headId)
-		_, err = b.openFile(b.headId, openBlockFileForReadOnly)
+		err = b.openSealedFile(b.headId)
 		if err != nil {
+			b.lock.Unlock()
 			return err
 		}
 
@@ -334,32 +533,53 @@ func (b *BlockTable) Append(item uint64, blob []byte) error {
 	}
 
 	defer b.lock.RUnlock()
+	headBytes := atomic.LoadUint32(&b.headBytes)
 	if _, err := b.head.Write(blob); err != nil {
+		// Drop any partially written data
+		_ = truncateBlockFile(b.head, int64(headBytes))
 		return err
 	}
-	newOffset := atomic.AddUint32(&b.headBytes, bLen)
 	idx := indexEntry{
 		filenum: atomic.LoadUint32(&b.headId),
-		offset:  newOffset,
+		offset:  headBytes + bLen,
 	}
 	// Write indexEntry
-	_, _ = b.index.Write(idx.marshallBinary())
+	if _, err := b.index.Write(idx.marshallBinary()); err != nil {
+		_ = truncateBlockFile(b.head, int64(headBytes))
+		_ = truncateBlockFile(b.index, indexOffset(item-uint64(b.itemOffset)+1))
+		return err
+	}
 
+	atomic.StoreUint32(&b.headBytes, idx.offset)
 	atomic.AddUint64(&b.items, 1)
 	return nil
 }
 
+// Sync flushes the index and the head data file to disk.
+func (b *BlockTable) Sync() error {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	if b.index == nil || b.head == nil {
+		return fmt.Errorf("closed")
+	}
+	if err := b.index.Sync(); err != nil {
+		return err
+	}
+	return b.head.Sync()
+}
+
 func (b *BlockTable) getBounds(item uint64) (uint32, uint32, uint32, error) {
 	buffer := make([]byte, indexEntrySize)
 	var startIdx, endIdx indexEntry
-	if _, err := b.index.ReadAt(buffer, int64((item+1)*indexEntrySize)); err != nil {
+	if _, err := b.indexReader.ReadAt(buffer, indexOffset(item+1)); err != nil {
 		return 0, 0, 0, err
 	}
 	if err := endIdx.unmarshalBinary(buffer); err != nil {
 		return 0, 0, 0, err
 	}
 	if item != 0 {
-		if _, err := b.index.ReadAt(buffer, int64(item*indexEntrySize)); err != nil {
+		if _, err := b.indexReader.ReadAt(buffer, indexOffset(item)); err != nil {
 			return 0, 0, 0, err
 		}
 		if err := startIdx.unmarshalBinary(buffer); err != nil {
@@ -379,7 +599,7 @@ func (b *BlockTable) preopen() (err error) {
 	b.releaseFilesAfter(0, false)
 
 	for i := b.tailId; i < b.headId; i++ {
-		if _, err = b.openFile(i, openBlockFileForReadOnly); err != nil {
+		if err = b.openSealedFile(i); err != nil {
 			return err
 		}
 	}
@@ -390,8 +610,7 @@ func (b *BlockTable) preopen() (err error) {
 func (b *BlockTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = b.files[num]; !exist {
-		name := fmt.Sprintf("%s.%04d.rdat", b.name, num)
-		f, err = opener(filepath.Join(b.path, name))
+		f, err = opener(b.dataFileName(num))
 		if err != nil {
 			return nil, err
 		}
@@ -400,6 +619,35 @@ func (b *BlockTable) openFile(num uint32, opener func(string) (*os.File, error))
 	return f, err
 }
 
+func (b *BlockTable) dataFileName(num uint32) string {
+	return filepath.Join(b.path, fmt.Sprintf("%s.%04d.rdat", b.name, num))
+}
+
+// openSealedFile opens a data file which is no longer appended to for reading,
+// and memory maps it if enabled.
+func (b *BlockTable) openSealedFile(num uint32) error {
+	f, err := b.openFile(num, openBlockFileForReadOnly)
+	if err != nil {
+		return err
+	}
+	if !b.mmap {
+		return nil
+	}
+	if _, exist := b.mmaps[num]; exist {
+		return nil
+	}
+	data, err := mmapFile(f)
+	if err != nil {
+		b.logger.WithFields(logrus.Fields{
+			"file": f.Name(),
+			"err":  err,
+		}).Warn("Failed to mmap data file, fall back to file reads")
+		return nil
+	}
+	b.mmaps[num] = data
+	return nil
+}
+
 // Close closes all opened files.
 func (b *BlockTable) Close() error {
 	b.lock.Lock()
@@ -410,7 +658,14 @@ func (b *BlockTable) Close() error {
 		errs = append(errs, err)
 	}
 	b.index = nil
+	b.indexReader = nil
 
+	for num, data := range b.mmaps {
+		if err := munmapFile(data); err != nil {
+			errs = append(errs, err)
+		}
+		delete(b.mmaps, num)
+	}
 	for _, f := range b.files {
 		if err := f.Close(); err != nil {
 			errs = append(errs, err)
@@ -427,6 +682,7 @@ func (b *BlockTable) Close() error {
 func (b *BlockTable) releaseFilesAfter(num uint32, remove bool) {
 	for fnum, f := range b.files {
 		if fnum > num {
+			b.unmapFile(fnum)
 			delete(b.files, fnum)
 			f.Close()
 			if remove {
@@ -437,12 +693,175 @@ func (b *BlockTable) releaseFilesAfter(num uint32, remove bool) {
 }
 
 func (b *BlockTable) releaseFile(num uint32) {
+	b.unmapFile(num)
 	if f, exist := b.files[num]; exist {
 		delete(b.files, num)
 		f.Close()
 	}
 }
 
+// unmapFile releases the mapping of a data file. The caller must hold the
+// write lock, so that no View callback still refers to the mapping.
+func (b *BlockTable) unmapFile(num uint32) {
+	if data, exist := b.mmaps[num]; exist {
+		delete(b.mmaps, num)
+		if err := munmapFile(data); err != nil {
+			b.logger.WithFields(logrus.Fields{
+				"file": num,
+				"err":  err,
+			}).Warn("Failed to munmap data file")
+		}
+	}
+}
+
+// checkIndexHeader verifies that the index file carries the current header.
+func checkIndexHeader(index *os.File) error {
+	header := make([]byte, indexHeaderSize)
+	if _, err := index.ReadAt(header, 0); err != nil {
+		return err
+	}
+	if string(header[:4]) != indexMagic {
+		return fmt.Errorf("invalid index file %s", index.Name())
+	}
+	if version := binary.BigEndian.Uint32(header[4:8]); version != indexVersion {
+		return fmt.Errorf("unsupported index version %d of %s", version, index.Name())
+	}
+	return nil
+}
+
+// isLegacyIndex reports whether the index file has no header and stores
+// 6-byte entries.
+//
+// Legacy indexes never discard items, so the offset of their first entry is
+// always zero and its bytes can't collide with the header magic.
+func isLegacyIndex(index *os.File) (bool, error) {
+	stat, err := index.Stat()
+	if err != nil {
+		return false, err
+	}
+	if stat.Size() == 0 {
+		return false, nil
+	}
+	if stat.Size() >= indexHeaderSize {
+		header := make([]byte, len(indexMagic))
+		if _, err := index.ReadAt(header, 0); err != nil {
+			return false, err
+		}
+		if string(header) == indexMagic {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// convertLegacyIndex writes the header and the given number of legacy entries
+// read from r in the current format to w.
+func convertLegacyIndex(r io.Reader, entries int64, w io.Writer) error {
+	if _, err := w.Write(indexHeader()); err != nil {
+		return err
+	}
+	var (
+		buffer = make([]byte, legacyIndexEntrySize)
+		entry  indexEntry
+	)
+	for i := int64(0); i < entries; i++ {
+		if _, err := io.ReadFull(r, buffer); err != nil {
+			return err
+		}
+		if err := entry.unmarshalLegacyBinary(buffer); err != nil {
+			return err
+		}
+		if _, err := w.Write(entry.marshallBinary()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readLegacyIndex returns a legacy index converted to the current format, a
+// partially written last entry is left out.
+func readLegacyIndex(index *os.File) ([]byte, error) {
+	stat, err := index.Stat()
+	if err != nil {
+		return nil, err
+	}
+	entries := stat.Size() / legacyIndexEntrySize
+	buf := bytes.NewBuffer(make([]byte, 0, indexOffset(uint64(entries))))
+	if err := convertLegacyIndex(bufio.NewReader(io.NewSectionReader(index, 0, stat.Size())), entries, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// migrateIndex converts a legacy index file into the current format. The
+// converted index is written to a temporary file first and then renamed over
+// the legacy one.
+func migrateIndex(name string, logger logrus.FieldLogger) error {
+	legacy, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer legacy.Close()
+
+	isLegacy, err := isLegacyIndex(legacy)
+	if err != nil || !isLegacy {
+		return err
+	}
+	stat, err := legacy.Stat()
+	if err != nil {
+		return err
+	}
+
+	entries := stat.Size() / legacyIndexEntrySize
+	logger.WithFields(logrus.Fields{
+		"index":   name,
+		"entries": entries,
+	}).Info("Migrating legacy block file index")
+
+	tmpName := name + ".tmp"
+	tmp, err := openBlockFileTruncated(tmpName)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpName)
+
+	writer := bufio.NewWriter(tmp)
+	if err := convertLegacyIndex(bufio.NewReader(legacy), entries, writer); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpName, name); err != nil {
+		return err
+	}
+	// Persist the rename, or new entries may end up in the legacy index
+	return syncDir(filepath.Dir(name))
+}
+
+// syncDir flushes the entries of the directory to disk.
+func syncDir(name string) error {
+	dir, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
 func truncateBlockFile(file *os.File, size int64) error {
 	if err := file.Truncate(size); err != nil {
 		return err
@@ -2,11 +2,16 @@ package blockfile
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io/ioutil"
+	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/meshplus/bitxhub-kit/log"
 	"github.com/meshplus/bitxhub-kit/types"
@@ -40,7 +45,7 @@ func TestBlockFileBasics(t *testing.T) {
 func TestBlockTableBasics(t *testing.T) {
 	// set cutoff at 50 bytes
 	f, err := newTable(os.TempDir(),
-		fmt.Sprintf("unittest-%d", rand.Uint64()), 2*1000*1000*1000, log.NewWithModule("blockfile_test"))
+		fmt.Sprintf("unittest-%d", rand.Uint64()), defaultConfig(), log.NewWithModule("blockfile_test"))
 	assert.Nil(t, err)
 	defer f.Close()
 	// Write 15 bytes 255 times, results in 85 files
@@ -120,14 +125,14 @@ func TestBlockTableBasicsClosing(t *testing.T) {
 		f      *BlockTable
 		err    error
 	)
-	f, err = newTable(os.TempDir(), fname, 2*1000*1000*1000, logger)
+	f, err = newTable(os.TempDir(), fname, defaultConfig(), logger)
 	assert.Nil(t, err)
 	// Write 15 bytes 255 times, results in 85 files
 	for x := 0; x < 255; x++ {
 		data := getChunk(15, x)
 		f.Append(uint64(x), data)
 		f.Close()
-		f, err = newTable(os.TempDir(), fname, 2*1000*1000*1000, logger)
+		f, err = newTable(os.TempDir(), fname, defaultConfig(), logger)
 		assert.Nil(t, err)
 	}
 	defer f.Close()
@@ -140,7 +145,7 @@ func TestBlockTableBasicsClosing(t *testing.T) {
 			t.Fatalf("test %d, got \n%x != \n%x", y, got, exp)
 		}
 		f.Close()
-		f, err = newTable(os.TempDir(), fname, 2*1000*1000*1000, logger)
+		f, err = newTable(os.TempDir(), fname, defaultConfig(), logger)
 		assert.Nil(t, err)
 	}
 }
@@ -150,7 +155,7 @@ func TestFreezerTruncate(t *testing.T) {
 	logger := log.NewWithModule("blockfile_test")
 
 	{ // Fill table
-		f, err := newTable(os.TempDir(), fname, 50, logger)
+		f, err := newTable(os.TempDir(), fname, &config{maxFileSize: 50}, logger)
 		assert.Nil(t, err)
 		// Write 15 bytes 30 times
 		for x := 0; x < 30; x++ {
@@ -164,7 +169,7 @@ func TestFreezerTruncate(t *testing.T) {
 	}
 	// Reopen, truncate
 	{
-		f, err := newTable(os.TempDir(), fname, 50, logger)
+		f, err := newTable(os.TempDir(), fname, &config{maxFileSize: 50}, logger)
 		assert.Nil(t, err)
 		defer f.Close()
 		// for x := 0; x < 20; x++ {
@@ -186,7 +191,7 @@ func TestFreezerReadAndTruncate(t *testing.T) {
 	fname := fmt.Sprintf("read_truncate-%d", rand.Uint64())
 	logger := log.NewWithModule("blockfile_test")
 	{ // Fill table
-		f, err := newTable(os.TempDir(), fname, 50, logger)
+		f, err := newTable(os.TempDir(), fname, &config{maxFileSize: 50}, logger)
 		assert.Nil(t, err)
 		// Write 15 bytes 30 times
 		for x := 0; x < 30; x++ {
@@ -200,7 +205,7 @@ func TestFreezerReadAndTruncate(t *testing.T) {
 	}
 	// Reopen and read all files
 	{
-		f, err := newTable(os.TempDir(), fname, 50, logger)
+		f, err := newTable(os.TempDir(), fname, &config{maxFileSize: 50}, logger)
 		assert.Nil(t, err)
 		if f.items != 30 {
 			f.Close()
@@ -225,7 +230,7 @@ func TestFreezerRepairFirstFile(t *testing.T) {
 	fname := fmt.Sprintf("truncationfirst-%d", rand.Uint64())
 	logger := log.NewWithModule("blockfile_test")
 	{ // Fill table
-		f, err := newTable(os.TempDir(), fname, 50, logger)
+		f, err := newTable(os.TempDir(), fname, &config{maxFileSize: 50}, logger)
 		assert.Nil(t, err)
 		// Write 80 bytes, splitting out into two files
 		f.Append(0, getChunk(40, 0xFF))
@@ -247,7 +252,7 @@ func TestFreezerRepairFirstFile(t *testing.T) {
 	}
 	// Reopen
 	{
-		f, err := newTable(os.TempDir(), fname, 50, logger)
+		f, err := newTable(os.TempDir(), fname, &config{maxFileSize: 50}, logger)
 		assert.Nil(t, err)
 		if f.items != 1 {
 			f.Close()
@@ -273,3 +278,415 @@ func assertFileSize(f string, size int64) error {
 
 	return nil
 }
+
+func TestBlockTableLegacyIndexMigration(t *testing.T) {
+	fname := fmt.Sprintf("legacy-index-%d", rand.Uint64())
+	logger := log.NewWithModule("blockfile_test")
+
+	// Write 3 items of 15 bytes with a legacy 6-byte index
+	var data, index []byte
+	index = append(index, make([]byte, legacyIndexEntrySize)...)
+	for x := 0; x < 3; x++ {
+		data = append(data, getChunk(15, x)...)
+		entry := make([]byte, legacyIndexEntrySize)
+		binary.BigEndian.PutUint32(entry[2:], uint32(len(data)))
+		index = append(index, entry...)
+	}
+	err := ioutil.WriteFile(filepath.Join(os.TempDir(), fmt.Sprintf("%s.0000.rdat", fname)), data, 0644)
+	assert.Nil(t, err)
+	err = ioutil.WriteFile(filepath.Join(os.TempDir(), fmt.Sprintf("%s.ridx", fname)), index, 0644)
+	assert.Nil(t, err)
+
+	f, err := newTable(os.TempDir(), fname, &config{maxFileSize: 50}, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), f.items)
+	for y := 0; y < 3; y++ {
+		got, err := f.Retrieve(uint64(y))
+		assert.Nil(t, err)
+		assert.Equal(t, getChunk(15, y), got)
+	}
+	err = f.Append(3, getChunk(15, 3))
+	assert.Nil(t, err)
+	f.Close()
+
+	err = assertFileSize(filepath.Join(os.TempDir(), fmt.Sprintf("%s.ridx", fname)), indexOffset(5))
+	assert.Nil(t, err)
+
+	// Reopen, the migrated index must be kept as is
+	f, err = newTable(os.TempDir(), fname, &config{maxFileSize: 50}, logger)
+	assert.Nil(t, err)
+	defer f.Close()
+	assert.Equal(t, uint64(4), f.items)
+	got, err := f.Retrieve(3)
+	assert.Nil(t, err)
+	assert.Equal(t, getChunk(15, 3), got)
+}
+
+func TestBlockTableLegacyIndexReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blockfile_legacy_read_only")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	logger := log.NewWithModule("blockfile_test")
+
+	var data, index []byte
+	index = append(index, make([]byte, legacyIndexEntrySize)...)
+	for x := 0; x < 3; x++ {
+		data = append(data, getChunk(15, x)...)
+		entry := make([]byte, legacyIndexEntrySize)
+		binary.BigEndian.PutUint32(entry[2:], uint32(len(data)))
+		index = append(index, entry...)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "legacy.0000.rdat"), data, 0644)
+	assert.Nil(t, err)
+	// A partially written entry must be reported, but neither used nor removed
+	idxName := filepath.Join(dir, "legacy.ridx")
+	err = ioutil.WriteFile(idxName, append(index, 0, 0), 0644)
+	assert.Nil(t, err)
+
+	f, err := newTable(dir, "legacy", &config{maxFileSize: 50, readOnly: true}, logger)
+	assert.Nil(t, err)
+	defer f.Close()
+	assert.Equal(t, uint64(3), f.items)
+	for y := 0; y < 3; y++ {
+		got, err := f.Retrieve(uint64(y))
+		assert.Nil(t, err)
+		assert.Equal(t, getChunk(15, y), got)
+	}
+
+	info, err := f.Inspect()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(index)+2), info.IndexSize)
+	assert.Equal(t, []string{"index file has 2 dangling bytes"}, info.Issues)
+
+	// Nothing was migrated or repaired
+	assert.Nil(t, assertFileSize(idxName, int64(len(index)+2)))
+	got, err := ioutil.ReadFile(idxName)
+	assert.Nil(t, err)
+	assert.Equal(t, append(index, 0, 0), got)
+}
+
+func TestBlockTableWideFileNumber(t *testing.T) {
+	idx := indexEntry{filenum: math.MaxUint16 + 2, offset: 42}
+	var got indexEntry
+	err := got.unmarshalBinary(idx.marshallBinary())
+	assert.Nil(t, err)
+	assert.Equal(t, idx, got)
+}
+
+func TestBlockFileMaxFileSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blockfile_max_size")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	f, err := NewBlockFile(dir, log.NewWithModule("blockfile_test"), WithMaxFileSize(50))
+	assert.Nil(t, err)
+	defer f.Close()
+	for x := 0; x < 10; x++ {
+		chunk := getChunk(15, x)
+		err = f.AppendBlock(uint64(x), chunk, chunk, chunk, chunk, chunk)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, uint32(3), f.tables[BlockFileBodiesTable].headId)
+}
+
+func TestBlockTableMmap(t *testing.T) {
+	fname := fmt.Sprintf("mmap-%d", rand.Uint64())
+	logger := log.NewWithModule("blockfile_test")
+
+	f, err := newTable(os.TempDir(), fname, &config{maxFileSize: 50, mmap: true}, logger)
+	assert.Nil(t, err)
+	// Write 15 bytes 30 times, results in 10 files
+	for x := 0; x < 30; x++ {
+		err := f.Append(uint64(x), getChunk(15, x))
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 9, len(f.mmaps))
+	for y := 0; y < 30; y++ {
+		got, err := f.Retrieve(uint64(y))
+		assert.Nil(t, err)
+		assert.Equal(t, getChunk(15, y), got)
+		err = f.View(uint64(y), func(blob []byte) error {
+			assert.Equal(t, getChunk(15, y), blob)
+			return nil
+		})
+		assert.Nil(t, err)
+	}
+
+	// Truncating back into a sealed file must release its mapping
+	err = f.truncate(10)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(f.mmaps))
+	_, err = f.Retrieve(10)
+	assert.Equal(t, fmt.Errorf("out of bounds"), err)
+	for x := 10; x < 30; x++ {
+		err := f.Append(uint64(x), getChunk(15, ^x))
+		assert.Nil(t, err)
+	}
+	f.Close()
+
+	// Reopen, all sealed files are mapped again
+	f, err = newTable(os.TempDir(), fname, &config{maxFileSize: 50, mmap: true}, logger)
+	assert.Nil(t, err)
+	defer f.Close()
+	assert.Equal(t, 9, len(f.mmaps))
+	for y := 0; y < 30; y++ {
+		exp := getChunk(15, y)
+		if y >= 10 {
+			exp = getChunk(15, ^y)
+		}
+		got, err := f.Retrieve(uint64(y))
+		assert.Nil(t, err)
+		assert.Equal(t, exp, got)
+	}
+}
+
+func TestBlockFileAppendRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blockfile_rollback")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	f, err := NewBlockFile(dir, log.NewWithModule("blockfile_test"))
+	assert.Nil(t, err)
+	defer f.Close()
+	chunk := getChunk(15, 1)
+	err = f.AppendBlock(0, chunk, chunk, chunk, chunk, chunk)
+	assert.Nil(t, err)
+
+	// Make a single table reject the next block
+	f.tables[BlockFileReceiptTable].items = 5
+	err = f.AppendBlock(1, chunk, chunk, chunk, chunk, chunk)
+	assert.NotNil(t, err)
+	num, err := f.Blocks()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), num)
+	for name, table := range f.tables {
+		assert.Equal(t, uint64(1), table.items, name)
+		assert.Equal(t, uint32(15), table.headBytes, name)
+	}
+
+	err = f.AppendBlock(1, chunk, chunk, chunk, chunk, chunk)
+	assert.Nil(t, err)
+	got, err := f.Get(BlockFileReceiptTable, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, chunk, got)
+}
+
+func TestBlockFileSyncPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blockfile_sync")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	logger := log.NewWithModule("blockfile_test")
+
+	_, err = NewBlockFile(dir, logger, WithSyncEveryN(0))
+	assert.NotNil(t, err)
+
+	f, err := NewBlockFile(dir, logger, WithSyncEveryN(3))
+	assert.Nil(t, err)
+	chunk := getChunk(15, 1)
+	for x := 0; x < 4; x++ {
+		err = f.AppendBlock(uint64(x), chunk, chunk, chunk, chunk, chunk)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, uint64(1), f.unsynced)
+	assert.Nil(t, f.Close())
+
+	f, err = NewBlockFile(dir, logger, WithSyncInterval(10*time.Millisecond))
+	assert.Nil(t, err)
+	defer f.Close()
+	err = f.AppendBlock(4, chunk, chunk, chunk, chunk, chunk)
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return atomic.LoadUint64(&f.unsynced) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestBlockFileReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blockfile_read_only")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	logger := log.NewWithModule("blockfile_test")
+
+	_, err = NewBlockFile(dir, logger, WithReadOnly())
+	assert.NotNil(t, err)
+
+	f, err := NewBlockFile(dir, logger, WithMaxFileSize(50))
+	assert.Nil(t, err)
+	defer f.Close()
+	for x := 0; x < 10; x++ {
+		chunk := getChunk(15, x)
+		err = f.AppendBlock(uint64(x), chunk, chunk, chunk, chunk, chunk)
+		assert.Nil(t, err)
+	}
+
+	// The writable instance holds the lock, a read-only one can still be opened
+	ro, err := NewBlockFile(dir, logger, WithReadOnly(), WithMaxFileSize(50))
+	assert.Nil(t, err)
+	defer ro.Close()
+	num, err := ro.Blocks()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), num)
+	got, err := ro.Get(BlockFileBodiesTable, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, getChunk(15, 9), got)
+
+	chunk := getChunk(15, 10)
+	err = ro.AppendBlock(10, chunk, chunk, chunk, chunk, chunk)
+	assert.Equal(t, errReadOnly, err)
+	err = ro.TruncateBlocks(1)
+	assert.Equal(t, errReadOnly, err)
+
+	infos, err := ro.Inspect()
+	assert.Nil(t, err)
+	assert.Equal(t, len(BlockFileSchema), len(infos))
+	for _, info := range infos {
+		assert.True(t, info.Consistent(), info.Issues)
+		assert.Equal(t, uint64(10), info.Items)
+		assert.Equal(t, 4, len(info.DataFiles))
+		assert.Equal(t, uint64(9), info.DataFiles[3].FirstItem)
+		assert.Equal(t, uint64(1), info.DataFiles[3].Items)
+	}
+
+	// Cut the head data file, the read-only instance must report it without repairing
+	head := f.tables[BlockFileReceiptTable].head.Name()
+	err = os.Truncate(head, 5)
+	assert.Nil(t, err)
+	info, err := ro.tables[BlockFileReceiptTable].Inspect()
+	assert.Nil(t, err)
+	assert.False(t, info.Consistent())
+	assert.Nil(t, assertFileSize(head, 5))
+}
+
+func TestBlockFileHashIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blockfile_hash_index")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	logger := log.NewWithModule("blockfile_test")
+
+	// Append some blocks before the index is enabled, they must be picked up
+	f, err := NewBlockFile(dir, logger)
+	assert.Nil(t, err)
+	_, err = f.GetByHash(BlockFileBodiesTable, types.NewHash([]byte{0}).Bytes())
+	assert.NotNil(t, err)
+	for x := 0; x < 3; x++ {
+		chunk := getChunk(15, x)
+		err = f.AppendBlock(uint64(x), types.NewHash([]byte{byte(x)}).Bytes(), chunk, chunk, chunk, chunk)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, f.Close())
+
+	f, err = NewBlockFile(dir, logger, WithHashIndex())
+	assert.Nil(t, err)
+	for x := 3; x < 6; x++ {
+		chunk := getChunk(15, x)
+		err = f.AppendBlock(uint64(x), types.NewHash([]byte{byte(x)}).Bytes(), chunk, chunk, chunk, chunk)
+		assert.Nil(t, err)
+	}
+	for x := 0; x < 6; x++ {
+		number, err := f.NumberByHash(types.NewHash([]byte{byte(x)}).Bytes())
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(x+1), number)
+		got, err := f.GetByHash(BlockFileBodiesTable, types.NewHash([]byte{byte(x)}).Bytes())
+		assert.Nil(t, err)
+		assert.Equal(t, getChunk(15, x), got)
+	}
+
+	// Same prefix, different hash
+	hash := types.NewHash([]byte{1}).Bytes()
+	hash[31] ^= 0xff
+	_, err = f.NumberByHash(hash)
+	assert.NotNil(t, err)
+
+	err = f.TruncateBlocks(4)
+	assert.Nil(t, err)
+	_, err = f.NumberByHash(types.NewHash([]byte{4}).Bytes())
+	assert.NotNil(t, err)
+	assert.Equal(t, uint64(4), f.hashIndex.items)
+	assert.Nil(t, assertFileSize(filepath.Join(dir, storageRoot, hashIndexName), 4*hashIndexEntrySize))
+
+	// A failed append must not leave the hash behind
+	f.tables[BlockFileReceiptTable].items = 5
+	chunk := getChunk(15, 4)
+	err = f.AppendBlock(4, types.NewHash([]byte{4}).Bytes(), chunk, chunk, chunk, chunk)
+	assert.NotNil(t, err)
+	_, err = f.NumberByHash(types.NewHash([]byte{4}).Bytes())
+	assert.NotNil(t, err)
+	assert.Nil(t, f.Close())
+
+	f, err = NewBlockFile(dir, logger, WithHashIndex())
+	assert.Nil(t, err)
+	defer f.Close()
+	number, err := f.NumberByHash(types.NewHash([]byte{3}).Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), number)
+}
+
+func TestBlockFileHashIndexStale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blockfile_hash_index_stale")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	logger := log.NewWithModule("blockfile_test")
+
+	hashOf := func(fork, x int) []byte {
+		return types.NewHash(bytes.Repeat([]byte{byte(fork), byte(x)}, 16)).Bytes()
+	}
+	appendBlocks := func(f *BlockFile, fork, from, to int) {
+		for x := from; x < to; x++ {
+			chunk := getChunk(15, x)
+			err := f.AppendBlock(uint64(x), hashOf(fork, x), chunk, chunk, chunk, chunk)
+			assert.Nil(t, err)
+		}
+	}
+
+	f, err := NewBlockFile(dir, logger, WithHashIndex())
+	assert.Nil(t, err)
+	appendBlocks(f, 0, 0, 5)
+	assert.Nil(t, f.Close())
+
+	// Replace the last blocks while the index is disabled
+	f, err = NewBlockFile(dir, logger)
+	assert.Nil(t, err)
+	assert.Nil(t, f.TruncateBlocks(2))
+	appendBlocks(f, 1, 2, 6)
+	assert.Nil(t, f.Close())
+
+	f, err = NewBlockFile(dir, logger, WithHashIndex())
+	assert.Nil(t, err)
+	defer f.Close()
+	for x := 0; x < 6; x++ {
+		fork := 1
+		if x < 2 {
+			fork = 0
+		}
+		number, err := f.NumberByHash(hashOf(fork, x))
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(x+1), number)
+	}
+	for x := 2; x < 5; x++ {
+		_, err := f.NumberByHash(hashOf(0, x))
+		assert.NotNil(t, err)
+	}
+	assert.Nil(t, assertFileSize(filepath.Join(dir, storageRoot, hashIndexName), 6*hashIndexEntrySize))
+	assert.Nil(t, f.Close())
+
+	// Replace blocks in the middle but keep the last one
+	f, err = NewBlockFile(dir, logger)
+	assert.Nil(t, err)
+	assert.Nil(t, f.TruncateBlocks(3))
+	appendBlocks(f, 2, 3, 5)
+	appendBlocks(f, 1, 5, 6)
+	assert.Nil(t, f.Close())
+
+	f, err = NewBlockFile(dir, logger, WithHashIndex())
+	assert.Nil(t, err)
+	defer f.Close()
+	for x := 3; x < 5; x++ {
+		number, err := f.NumberByHash(hashOf(2, x))
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(x+1), number)
+		_, err = f.NumberByHash(hashOf(1, x))
+		assert.NotNil(t, err)
+	}
+	number, err := f.NumberByHash(hashOf(1, 5))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(6), number)
+}
@@ -0,0 +1,118 @@
+package blockfile
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultMaxFileSize is the size at which a data file is sealed and a new
+	// head file is opened.
+	defaultMaxFileSize = 2 * 1000 * 1000 * 1000
+)
+
+type syncPolicy int
+
+const (
+	// syncNone leaves flushing written data to the operating system.
+	syncNone syncPolicy = iota
+	// syncEveryBlock flushes all tables after every appended block.
+	syncEveryBlock
+	// syncEveryN flushes all tables once every syncBlocks appended blocks.
+	syncEveryN
+	// syncInterval flushes all tables every syncInterval if anything was appended.
+	syncInterval
+)
+
+type config struct {
+	maxFileSize  uint32
+	mmap         bool
+	readOnly     bool
+	hashIndex    bool
+	syncPolicy   syncPolicy
+	syncBlocks   uint64
+	syncInterval time.Duration
+}
+
+type Option func(*config)
+
+// WithMaxFileSize sets the max size in bytes of a single data file.
+func WithMaxFileSize(maxFileSize uint32) Option {
+	return func(c *config) {
+		c.maxFileSize = maxFileSize
+	}
+}
+
+// WithMmap enables memory mapped reads of sealed (non-head) data files.
+func WithMmap(mmap bool) Option {
+	return func(c *config) {
+		c.mmap = mmap
+	}
+}
+
+// WithReadOnly opens the block file without taking the file lock and without
+// repairing the tables, so that the data of a running node can be inspected.
+func WithReadOnly() Option {
+	return func(c *config) {
+		c.readOnly = true
+	}
+}
+
+// WithHashIndex maintains an index from block hashes to block numbers, which
+// enables GetByHash.
+func WithHashIndex() Option {
+	return func(c *config) {
+		c.hashIndex = true
+	}
+}
+
+// WithSyncEveryBlock makes every appended block durable before AppendBlock returns.
+func WithSyncEveryBlock() Option {
+	return func(c *config) {
+		c.syncPolicy = syncEveryBlock
+	}
+}
+
+// WithSyncEveryN flushes the appended blocks to disk once every n blocks. The
+// blocks appended in between are not durable yet.
+func WithSyncEveryN(n uint64) Option {
+	return func(c *config) {
+		c.syncPolicy = syncEveryN
+		c.syncBlocks = n
+	}
+}
+
+// WithSyncInterval flushes the appended blocks to disk periodically.
+func WithSyncInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.syncPolicy = syncInterval
+		c.syncInterval = interval
+	}
+}
+
+func defaultConfig() *config {
+	return &config{
+		maxFileSize: defaultMaxFileSize,
+	}
+}
+
+func generateConfig(opts ...Option) (*config, error) {
+	conf := defaultConfig()
+	for _, opt := range opts {
+		opt(conf)
+	}
+	if conf.maxFileSize == 0 {
+		conf.maxFileSize = defaultMaxFileSize
+	}
+	if conf.readOnly {
+		conf.syncPolicy = syncNone
+	}
+	if conf.syncPolicy == syncEveryN && conf.syncBlocks == 0 {
+		return nil, fmt.Errorf("sync block count must be positive")
+	}
+	if conf.syncPolicy == syncInterval && conf.syncInterval <= 0 {
+		return nil, fmt.Errorf("sync interval must be positive")
+	}
+
+	return conf, nil
+}
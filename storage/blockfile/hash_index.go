@@ -0,0 +1,260 @@
+package blockfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	hashIndexName = "hashes.hidx"
+
+	// hashIndexEntrySize is the size of the hash prefix stored per block.
+	hashIndexEntrySize = 8
+)
+
+// hashIndex maps block hashes to block numbers. On disk it stores the first
+// 8 bytes of every block hash in block order, in memory it keeps a map from
+// those prefixes to item numbers. Prefix collisions are resolved by comparing
+// the full hash stored in the hashes table.
+//
+// The index is derived data: on open every stored prefix is checked against
+// the hashes table and the index is rebuilt from the first one that
+// disagrees.
+type hashIndex struct {
+	file     *os.File
+	items    uint64
+	readOnly bool
+
+	lookup     map[uint64]uint64   // hash prefix -> item
+	collisions map[uint64][]uint64 // hash prefix -> further items with the same prefix
+
+	logger logrus.FieldLogger
+	lock   sync.RWMutex
+}
+
+func hashKey(hash []byte) uint64 {
+	var b [hashIndexEntrySize]byte
+	copy(b[:], hash)
+	return binary.BigEndian.Uint64(b[:])
+}
+
+// newHashIndex opens the hash index and brings it in line with the first
+// items of the hashes table.
+func newHashIndex(path string, hashes *BlockTable, items uint64, readOnly bool, logger logrus.FieldLogger) (*hashIndex, error) {
+	opener := openBlockFileForAppend
+	if readOnly {
+		opener = openBlockFileForReadOnly
+	}
+	file, err := opener(filepath.Join(path, hashIndexName))
+	if err != nil {
+		// A read-only index can live in memory only
+		if !readOnly || !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	idx := &hashIndex{
+		file:       file,
+		readOnly:   readOnly,
+		lookup:     make(map[uint64]uint64),
+		collisions: make(map[uint64][]uint64),
+		logger:     logger,
+	}
+	if err := idx.load(hashes, items); err != nil {
+		idx.Close()
+		return nil, err
+	}
+	return idx, nil
+}
+
+func (idx *hashIndex) load(hashes *BlockTable, items uint64) error {
+	var size int64
+	if idx.file != nil {
+		stat, err := idx.file.Stat()
+		if err != nil {
+			return err
+		}
+		size = stat.Size()
+	}
+	stored := uint64(size / hashIndexEntrySize)
+	if stored > items {
+		stored = items
+	}
+	if !idx.readOnly && size != int64(stored*hashIndexEntrySize) {
+		idx.logger.WithFields(logrus.Fields{
+			"indexed": size / hashIndexEntrySize,
+			"blocks":  items,
+		}).Warn("Truncating dangling hash index")
+		if err := truncateBlockFile(idx.file, int64(stored*hashIndexEntrySize)); err != nil {
+			return err
+		}
+	}
+
+	// Blocks truncated and appended again while the index was disabled leave
+	// stale prefixes behind, keep the index up to the first of them
+	if stored != 0 {
+		reader := bufio.NewReader(io.NewSectionReader(idx.file, 0, int64(stored*hashIndexEntrySize)))
+		buffer := make([]byte, hashIndexEntrySize)
+		for item := uint64(0); item < stored; item++ {
+			if _, err := io.ReadFull(reader, buffer); err != nil {
+				return err
+			}
+			hash, err := hashes.Retrieve(item)
+			if err != nil {
+				return err
+			}
+			key := binary.BigEndian.Uint64(buffer)
+			if key == hashKey(hash) {
+				idx.add(key, item)
+				continue
+			}
+
+			idx.logger.WithFields(logrus.Fields{
+				"indexed": stored,
+				"stale":   item,
+				"blocks":  items,
+			}).Warn("Discarding stale hash index")
+			stored = item
+			if !idx.readOnly {
+				if err := truncateBlockFile(idx.file, int64(stored*hashIndexEntrySize)); err != nil {
+					return err
+				}
+			}
+			break
+		}
+	}
+	idx.items = stored
+
+	// Rebuild the missing tail from the hashes table
+	if stored < items {
+		idx.logger.WithFields(logrus.Fields{
+			"indexed": stored,
+			"blocks":  items,
+		}).Info("Rebuilding hash index")
+	}
+	for item := stored; item < items; item++ {
+		hash, err := hashes.Retrieve(item)
+		if err != nil {
+			return err
+		}
+		if err := idx.append(item, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (idx *hashIndex) add(key uint64, item uint64) {
+	if _, exist := idx.lookup[key]; exist {
+		idx.collisions[key] = append(idx.collisions[key], item)
+		return
+	}
+	idx.lookup[key] = item
+}
+
+func (idx *hashIndex) remove(key uint64, item uint64) {
+	if others := idx.collisions[key]; len(others) != 0 {
+		if others[len(others)-1] == item {
+			idx.collisions[key] = others[:len(others)-1]
+		}
+		if len(idx.collisions[key]) == 0 {
+			delete(idx.collisions, key)
+		}
+		return
+	}
+	if idx.lookup[key] == item {
+		delete(idx.lookup, key)
+	}
+}
+
+// append adds the hash of the given item. Read-only indexes are only updated
+// in memory.
+func (idx *hashIndex) append(item uint64, hash []byte) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if idx.items != item {
+		return fmt.Errorf("appending unexpected hash index item: want %d, have %d", idx.items, item)
+	}
+	key := hashKey(hash)
+	if !idx.readOnly {
+		buffer := make([]byte, hashIndexEntrySize)
+		binary.BigEndian.PutUint64(buffer, key)
+		if _, err := idx.file.Write(buffer); err != nil {
+			_ = truncateBlockFile(idx.file, int64(item*hashIndexEntrySize))
+			return err
+		}
+	}
+	idx.add(key, item)
+	idx.items++
+	return nil
+}
+
+// truncate discards all items above the given threshold.
+func (idx *hashIndex) truncate(items uint64) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if idx.items <= items {
+		return nil
+	}
+	buffer := make([]byte, hashIndexEntrySize)
+	for item := idx.items; item > items; item-- {
+		if _, err := idx.file.ReadAt(buffer, int64((item-1)*hashIndexEntrySize)); err != nil {
+			return err
+		}
+		idx.remove(binary.BigEndian.Uint64(buffer), item-1)
+	}
+	if err := truncateBlockFile(idx.file, int64(items*hashIndexEntrySize)); err != nil {
+		return err
+	}
+	idx.items = items
+	return nil
+}
+
+// get returns the item whose full hash, looked up in the hashes table, is
+// equal to the given hash.
+func (idx *hashIndex) get(hash []byte, hashes *BlockTable) (uint64, error) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	key := hashKey(hash)
+	item, exist := idx.lookup[key]
+	if !exist {
+		return 0, fmt.Errorf("unknown block hash %x", hash)
+	}
+	for _, candidate := range append([]uint64{item}, idx.collisions[key]...) {
+		stored, err := hashes.Retrieve(candidate)
+		if err != nil {
+			return 0, err
+		}
+		if bytes.Equal(stored, hash) {
+			return candidate, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown block hash %x", hash)
+}
+
+func (idx *hashIndex) sync() error {
+	if idx.readOnly {
+		return nil
+	}
+	return idx.file.Sync()
+}
+
+func (idx *hashIndex) Close() error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if idx.file == nil {
+		return nil
+	}
+	return idx.file.Close()
+}
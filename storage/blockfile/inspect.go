@@ -0,0 +1,136 @@
+package blockfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync/atomic"
+)
+
+// TableInfo describes the on-disk state of a block table.
+type TableInfo struct {
+	Name       string          `json:"name"`
+	Items      uint64          `json:"items"`
+	ItemOffset uint32          `json:"item_offset"`
+	TailFile   uint32          `json:"tail_file"`
+	HeadFile   uint32          `json:"head_file"`
+	HeadBytes  uint32          `json:"head_bytes"`
+	IndexSize  int64           `json:"index_size"` // Bytes of the index file on disk
+	DataFiles  []*DataFileInfo `json:"data_files"`
+	Issues     []string        `json:"issues"` // Inconsistencies between index and data files
+}
+
+// DataFileInfo describes a single data file of a block table.
+type DataFileInfo struct {
+	Number    uint32 `json:"number"`
+	FirstItem uint64 `json:"first_item"`
+	Items     uint64 `json:"items"`
+	Indexed   uint32 `json:"indexed"` // Bytes referenced by the index
+	Size      int64  `json:"size"`    // Bytes stored on disk
+}
+
+// Consistent reports whether the index and the data files of the table agree.
+func (info *TableInfo) Consistent() bool {
+	return len(info.Issues) == 0
+}
+
+// Inspect walks the index of every table and checks it against the data
+// files. It never modifies anything, so it is best used together with
+// WithReadOnly on the data of a running node.
+func (bf *BlockFile) Inspect() ([]*TableInfo, error) {
+	names := make([]string, 0, len(bf.tables))
+	for name := range bf.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos := make([]*TableInfo, 0, len(names))
+	for _, name := range names {
+		info, err := bf.tables[name].Inspect()
+		if err != nil {
+			return nil, fmt.Errorf("inspect table %s: %w", name, err)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Inspect returns the state of the table and its data files.
+func (b *BlockTable) Inspect() (*TableInfo, error) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	if b.index == nil {
+		return nil, fmt.Errorf("closed")
+	}
+	info := &TableInfo{
+		Name:       b.name,
+		Items:      atomic.LoadUint64(&b.items),
+		ItemOffset: b.itemOffset,
+		TailFile:   b.tailId,
+		HeadFile:   atomic.LoadUint32(&b.headId),
+		HeadBytes:  atomic.LoadUint32(&b.headBytes),
+	}
+	entries := info.Items - uint64(info.ItemOffset)
+	stat, err := b.index.Stat()
+	if err != nil {
+		return nil, err
+	}
+	info.IndexSize = stat.Size()
+	indexed := indexOffset(entries + 1)
+	if b.legacyIndex {
+		indexed = int64(entries+1) * legacyIndexEntrySize
+	}
+	if info.IndexSize > indexed {
+		info.Issues = append(info.Issues, fmt.Sprintf("index file has %d dangling bytes", info.IndexSize-indexed))
+	} else if info.IndexSize < indexed {
+		info.Issues = append(info.Issues, fmt.Sprintf("index file has %d bytes, %d items expect %d", info.IndexSize, entries, indexed))
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(b.indexReader, indexOffset(1), int64(entries)*indexEntrySize))
+	var (
+		buffer = make([]byte, indexEntrySize)
+		entry  indexEntry
+		last   = indexEntry{filenum: info.TailFile}
+		file   = &DataFileInfo{Number: info.TailFile, FirstItem: uint64(info.ItemOffset)}
+	)
+	for i := uint64(0); i < entries; i++ {
+		if _, err := io.ReadFull(reader, buffer); err != nil {
+			return nil, err
+		}
+		if err := entry.unmarshalBinary(buffer); err != nil {
+			return nil, err
+		}
+		item := uint64(info.ItemOffset) + i
+		switch {
+		case entry.filenum < last.filenum:
+			info.Issues = append(info.Issues, fmt.Sprintf("item %d points back to data file %d from %d", item, entry.filenum, last.filenum))
+		case entry.filenum > last.filenum:
+			info.DataFiles = append(info.DataFiles, file)
+			file = &DataFileInfo{Number: entry.filenum, FirstItem: item}
+		case entry.offset < last.offset:
+			info.Issues = append(info.Issues, fmt.Sprintf("item %d ends at %d before its predecessor at %d", item, entry.offset, last.offset))
+		}
+		file.Items++
+		file.Indexed = entry.offset
+		last = entry
+	}
+	info.DataFiles = append(info.DataFiles, file)
+
+	for _, file := range info.DataFiles {
+		stat, err := os.Stat(b.dataFileName(file.Number))
+		if err != nil {
+			info.Issues = append(info.Issues, fmt.Sprintf("data file %d: %v", file.Number, err))
+			continue
+		}
+		file.Size = stat.Size()
+		if file.Size < int64(file.Indexed) {
+			info.Issues = append(info.Issues, fmt.Sprintf("data file %d has %d bytes, index expects %d", file.Number, file.Size, file.Indexed))
+		} else if file.Size > int64(file.Indexed) {
+			info.Issues = append(info.Issues, fmt.Sprintf("data file %d has %d dangling bytes", file.Number, file.Size-int64(file.Indexed)))
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,17 @@
+//go:build windows || js || plan9
+// +build windows js plan9
+
+package blockfile
+
+import (
+	"fmt"
+	"os"
+)
+
+func mmapFile(f *os.File) ([]byte, error) {
+	return nil, fmt.Errorf("mmap is not supported on this platform")
+}
+
+func munmapFile(data []byte) error {
+	return nil
+}
@@ -0,0 +1,29 @@
+//go:build !windows && !js && !plan9
+// +build !windows,!js,!plan9
+
+package blockfile
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the whole content of f into memory as read-only.
+func mmapFile(f *os.File) ([]byte, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if stat.Size() == 0 {
+		return []byte{}, nil
+	}
+	return syscall.Mmap(int(f.Fd()), 0, int(stat.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmapFile releases a mapping created by mmapFile.
+func munmapFile(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return syscall.Munmap(data)
+}